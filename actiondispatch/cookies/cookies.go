@@ -0,0 +1,241 @@
+// Package cookies reads and writes the signed and encrypted cookies of
+// ActionDispatch::Cookies.
+package cookies
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/atitan/activesupport-go/keygenerator"
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/encryptor"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+// MaxCookieSize is the largest cookie value Rails accepts to write.
+const MaxCookieSize = 4096
+
+var (
+	CookieOverflowError = errors.New("cookies: cookie overflow")
+	InvalidCookieError  = errors.New("cookies: invalid cookie")
+)
+
+// Config mirrors the action_dispatch settings which shape the cookie jars.
+type Config struct {
+	// KeyGenerator should cache keys, as every jar derives four of them.
+	KeyGenerator keygenerator.Generator
+
+	SignedCookieSalt                 string
+	EncryptedCookieSalt              string
+	EncryptedSignedCookieSalt        string
+	AuthenticatedEncryptedCookieSalt string
+
+	// UseAuthenticatedCookieEncryption encrypts with aes-256-gcm, and still
+	// reads cookies encrypted with the legacy aes-256-cbc scheme.
+	UseAuthenticatedCookieEncryption bool
+	UseCookiesWithMetadata           bool
+
+	SignedCookieDigest func() hash.Hash
+	// CookiesDigest signs the legacy aes-256-cbc encrypted cookies.
+	CookiesDigest func() hash.Hash
+	Serializer    codec.Serializer
+
+	// Rotations are tried in order after the primary config, like
+	// config.action_dispatch.cookies_rotations. They should be built with the
+	// codec returned by Codec.
+	SignedRotations    []*verifier.Verifier
+	EncryptedRotations []*encryptor.Encryptor
+
+	// OnRotation is called with the cookie name whenever a cookie is read
+	// through a rotation, so it can be written again with the primary config.
+	OnRotation func(name string)
+
+	// Now replaces time.Now when computing and checking expiries.
+	Now func() time.Time
+}
+
+// DefaultConfig returns the cookie settings of a Rails 7+ application, with
+// keys derived from secretKeyBase.
+func DefaultConfig(secretKeyBase []byte) Config {
+	return NewConfig(keygenerator.NewCaching(keygenerator.New(secretKeyBase, 1000, sha256.New)))
+}
+
+// NewConfig returns the cookie settings of a Rails 7+ application, with keys
+// derived by keys, e.g. the key generator of a rails.Application.
+func NewConfig(keys keygenerator.Generator) Config {
+	serializer, err := codec.SerializerWithFallback("json")
+	if err != nil {
+		panic(err)
+	}
+
+	return Config{
+		KeyGenerator: keys,
+
+		SignedCookieSalt:                 "signed cookie",
+		EncryptedCookieSalt:              "encrypted cookie",
+		EncryptedSignedCookieSalt:        "signed encrypted cookie",
+		AuthenticatedEncryptedCookieSalt: "authenticated encrypted cookie",
+
+		UseAuthenticatedCookieEncryption: true,
+		UseCookiesWithMetadata:           true,
+
+		SignedCookieDigest: sha1.New,
+		CookiesDigest:      sha1.New,
+		Serializer:         serializer,
+	}
+}
+
+// Codec returns the message codec of the cookie jars. Cookie values are
+// serialized on their own, then wrapped in the legacy JSON envelope.
+func (c Config) Codec() codec.Codec {
+	msgCodec := codec.New(false, true).WithSerializer(c.Serializer)
+	if c.Now != nil {
+		msgCodec = msgCodec.WithClock(c.Now)
+	}
+
+	return msgCodec
+}
+
+type Jar struct {
+	config    Config
+	signed    *verifier.Verifier
+	encrypted *encryptor.Encryptor
+}
+
+func New(config Config) *Jar {
+	if config.KeyGenerator == nil {
+		panic("cookies: empty key generator")
+	}
+	if config.Serializer == nil {
+		panic("cookies: empty serializer")
+	}
+
+	msgCodec := config.Codec()
+	keys := config.KeyGenerator
+
+	signed := verifier.New(msgCodec, config.SignedCookieDigest, keys.GenerateKey([]byte(config.SignedCookieSalt), 64)).
+		WithRotations(config.SignedRotations...)
+
+	legacySecret := keys.GenerateKey([]byte(config.EncryptedCookieSalt), 32)
+	legacySignSecret := keys.GenerateKey([]byte(config.EncryptedSignedCookieSalt), 64)
+	legacy := encryptor.New(msgCodec, false, legacySecret, config.CookiesDigest, legacySignSecret)
+
+	var encrypted *encryptor.Encryptor
+	if config.UseAuthenticatedCookieEncryption {
+		secret := keys.GenerateKey([]byte(config.AuthenticatedEncryptedCookieSalt), 32)
+		encrypted = encryptor.New(msgCodec, true, secret, nil, nil).
+			WithRotations(config.EncryptedRotations...).
+			WithRotations(legacy)
+	} else {
+		encrypted = legacy.WithRotations(config.EncryptedRotations...)
+	}
+
+	return &Jar{
+		config:    config,
+		signed:    signed,
+		encrypted: encrypted,
+	}
+}
+
+// ReadSigned verifies the value of a cookie sent by the browser, like
+// cookies.signed[name], and stores the result in v.
+func (j *Jar) ReadSigned(cookie *http.Cookie, v any) error {
+	sealed, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return InvalidCookieError
+	}
+
+	return j.read(cookie.Name, func(opt codec.MetadataOption, onRotation func()) error {
+		return j.signed.WithOnRotation(onRotation).Verify([]byte(sealed), v, opt)
+	})
+}
+
+// WriteSigned signs v into the value of cookie, like cookies.signed[name] =.
+// The expiry of the cookie is embedded in the message.
+func (j *Jar) WriteSigned(cookie *http.Cookie, v any) error {
+	sealed, err := j.signed.Generate(v, j.metadata(cookie))
+	if err != nil {
+		return err
+	}
+
+	return setValue(cookie, sealed)
+}
+
+// ReadEncrypted decrypts the value of a cookie sent by the browser, like
+// cookies.encrypted[name], and stores the result in v.
+func (j *Jar) ReadEncrypted(cookie *http.Cookie, v any) error {
+	encrypted, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return InvalidCookieError
+	}
+
+	return j.read(cookie.Name, func(opt codec.MetadataOption, onRotation func()) error {
+		return j.encrypted.WithOnRotation(onRotation).Decrypt([]byte(encrypted), v, opt)
+	})
+}
+
+// WriteEncrypted encrypts v into the value of cookie, like
+// cookies.encrypted[name] =. The expiry of the cookie is embedded in the
+// message.
+func (j *Jar) WriteEncrypted(cookie *http.Cookie, v any) error {
+	encrypted, err := j.encrypted.Encrypt(v, j.metadata(cookie))
+	if err != nil {
+		return err
+	}
+
+	return setValue(cookie, encrypted)
+}
+
+// read tries the purpose of the cookie first, then no purpose at all for
+// cookies written without metadata. Like Rails, this does not depend on
+// UseCookiesWithMetadata, which only applies to written cookies.
+func (j *Jar) read(name string, fn func(opt codec.MetadataOption, onRotation func()) error) error {
+	rotated := false
+	onRotation := func() { rotated = true }
+
+	err := fn(codec.MetadataOption{Purpose: "cookie." + name}, onRotation)
+	if err != nil && fn(codec.MetadataOption{}, onRotation) == nil {
+		err = nil
+	}
+
+	if err == nil && rotated && j.config.OnRotation != nil {
+		j.config.OnRotation(name)
+	}
+
+	return err
+}
+
+// metadata embeds the expiry of cookie, and its purpose with
+// UseCookiesWithMetadata, like cookie_metadata in Rails.
+func (j *Jar) metadata(cookie *http.Cookie) codec.MetadataOption {
+	var opt codec.MetadataOption
+	if j.config.UseCookiesWithMetadata {
+		opt.Purpose = "cookie." + cookie.Name
+	}
+
+	switch {
+	case !cookie.Expires.IsZero():
+		opt.ExpiresAt = &cookie.Expires
+	case cookie.MaxAge > 0:
+		expiresIn := time.Duration(cookie.MaxAge) * time.Second
+		opt.ExpiresIn = &expiresIn
+	}
+
+	return opt
+}
+
+// setValue checks the size of the value like Rails, then escapes it like
+// Rack does.
+func setValue(cookie *http.Cookie, value []byte) error {
+	if len(value) > MaxCookieSize {
+		return CookieOverflowError
+	}
+
+	cookie.Value = url.QueryEscape(string(value))
+	return nil
+}
@@ -0,0 +1,218 @@
+package cookies
+
+import (
+	"crypto/sha1"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/encryptor"
+	"github.com/atitan/activesupport-go/message/verifier"
+	"github.com/atitan/activesupport-go/testing/timehelpers"
+)
+
+var secretKeyBase = []byte("b3c631c314c0bbca50c1b2843150fe33")
+
+func TestSignedRoundTrip(t *testing.T) {
+	jar := New(DefaultConfig(secretKeyBase))
+
+	cookie := &http.Cookie{Name: "user_id"}
+	if err := jar.WriteSigned(cookie, "45"); err != nil {
+		t.Error(err)
+		return
+	}
+	if strings.ContainsAny(cookie.Value, "=/+") {
+		t.Errorf("value not escaped: %q", cookie.Value)
+	}
+
+	var data string
+	if err := jar.ReadSigned(cookie, &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "45" {
+		t.Errorf("data mismatch: %q", data)
+	}
+
+	renamed := &http.Cookie{Name: "other", Value: cookie.Value}
+	if err := jar.ReadSigned(renamed, &data); err == nil {
+		t.Error("expected error for cookie with another name")
+	}
+}
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	jar := New(DefaultConfig(secretKeyBase))
+
+	cookie := &http.Cookie{Name: "favorite"}
+	if err := jar.WriteEncrypted(cookie, map[string]any{"color": "blue"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var data map[string]any
+	if err := jar.ReadEncrypted(cookie, &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data["color"] != "blue" {
+		t.Errorf("data mismatch: %v", data)
+	}
+
+	// Encrypted cookies cannot be read as signed ones
+	if err := jar.ReadSigned(cookie, &data); err == nil {
+		t.Error("expected error for encrypted cookie")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	for _, withMetadata := range []bool{true, false} {
+		clock := timehelpers.New()
+		clock.TravelTo(time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC))
+
+		config := DefaultConfig(secretKeyBase)
+		config.Now = clock.Now
+		config.UseCookiesWithMetadata = withMetadata
+		jar := New(config)
+
+		signed := &http.Cookie{Name: "remember", MaxAge: 3600}
+		if err := jar.WriteSigned(signed, "1"); err != nil {
+			t.Error(err)
+			return
+		}
+		encrypted := &http.Cookie{Name: "remember", MaxAge: 3600}
+		if err := jar.WriteEncrypted(encrypted, "1"); err != nil {
+			t.Error(err)
+			return
+		}
+
+		var data string
+		if err := jar.ReadSigned(signed, &data); err != nil {
+			t.Errorf("metadata %v: %v", withMetadata, err)
+		}
+		if err := jar.ReadEncrypted(encrypted, &data); err != nil {
+			t.Errorf("metadata %v: %v", withMetadata, err)
+		}
+
+		clock.Travel(time.Hour)
+		if err := jar.ReadSigned(signed, &data); !errors.Is(err, codec.ExpiredError) {
+			t.Errorf("metadata %v: expected ExpiredError, got: %v", withMetadata, err)
+		}
+		if err := jar.ReadEncrypted(encrypted, &data); !errors.Is(err, codec.ExpiredError) {
+			t.Errorf("metadata %v: expected ExpiredError, got: %v", withMetadata, err)
+		}
+	}
+}
+
+func TestReadWithoutPurpose(t *testing.T) {
+	config := DefaultConfig(secretKeyBase)
+	config.UseCookiesWithMetadata = false
+	legacyJar := New(config)
+
+	cookie := &http.Cookie{Name: "user_id"}
+	if err := legacyJar.WriteEncrypted(cookie, "45"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var data string
+	if err := New(DefaultConfig(secretKeyBase)).ReadEncrypted(cookie, &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "45" {
+		t.Errorf("data mismatch: %q", data)
+	}
+
+	// Cookies with a purpose are read without UseCookiesWithMetadata too
+	cookie = &http.Cookie{Name: "user_id"}
+	if err := New(DefaultConfig(secretKeyBase)).WriteEncrypted(cookie, "46"); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := legacyJar.ReadEncrypted(cookie, &data); err != nil || data != "46" {
+		t.Errorf("unexpected result: %q, %v", data, err)
+	}
+
+	cookie.Name = "other"
+	if err := legacyJar.ReadEncrypted(cookie, &data); !errors.Is(err, codec.MismatchedPurposeError) {
+		t.Errorf("expected MismatchedPurposeError, got: %v", err)
+	}
+}
+
+func TestReadLegacyEncryption(t *testing.T) {
+	config := DefaultConfig(secretKeyBase)
+	config.UseAuthenticatedCookieEncryption = false
+	legacyJar := New(config)
+
+	cookie := &http.Cookie{Name: "user_id"}
+	if err := legacyJar.WriteEncrypted(cookie, "45"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	rotated := ""
+	config = DefaultConfig(secretKeyBase)
+	config.OnRotation = func(name string) { rotated = name }
+
+	var data string
+	if err := New(config).ReadEncrypted(cookie, &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "45" {
+		t.Errorf("data mismatch: %q", data)
+	}
+	if rotated != "user_id" {
+		t.Errorf("rotation not reported: %q", rotated)
+	}
+}
+
+func TestRotations(t *testing.T) {
+	oldConfig := DefaultConfig([]byte("old secret key base"))
+	oldJar := New(oldConfig)
+
+	signedCookie := &http.Cookie{Name: "user_id"}
+	if err := oldJar.WriteSigned(signedCookie, "45"); err != nil {
+		t.Error(err)
+		return
+	}
+	encryptedCookie := &http.Cookie{Name: "user_id"}
+	if err := oldJar.WriteEncrypted(encryptedCookie, "45"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	keys := oldConfig.KeyGenerator
+	config := DefaultConfig(secretKeyBase)
+	config.SignedRotations = []*verifier.Verifier{
+		verifier.New(config.Codec(), sha1.New, keys.GenerateKey([]byte("signed cookie"), 64)),
+	}
+	config.EncryptedRotations = []*encryptor.Encryptor{
+		encryptor.New(config.Codec(), true, keys.GenerateKey([]byte("authenticated encrypted cookie"), 32), nil, nil),
+	}
+	jar := New(config)
+
+	var data string
+	if err := jar.ReadSigned(signedCookie, &data); err != nil || data != "45" {
+		t.Errorf("signed rotation failed: %q, %v", data, err)
+	}
+	data = ""
+	if err := jar.ReadEncrypted(encryptedCookie, &data); err != nil || data != "45" {
+		t.Errorf("encrypted rotation failed: %q, %v", data, err)
+	}
+}
+
+func TestCookieOverflow(t *testing.T) {
+	jar := New(DefaultConfig(secretKeyBase))
+
+	cookie := &http.Cookie{Name: "big"}
+	if err := jar.WriteSigned(cookie, strings.Repeat("a", MaxCookieSize)); !errors.Is(err, CookieOverflowError) {
+		t.Errorf("expected CookieOverflowError, got: %v", err)
+	}
+	if cookie.Value != "" {
+		t.Errorf("value set on overflow: %q", cookie.Value)
+	}
+}
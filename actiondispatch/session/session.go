@@ -0,0 +1,176 @@
+// Package session reads and writes the session cookie of
+// ActionDispatch::Session::CookieStore.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/atitan/activesupport-go/actiondispatch/cookies"
+)
+
+const (
+	idKey        = "session_id"
+	csrfTokenKey = "_csrf_token"
+	flashKey     = "flash"
+)
+
+// Session holds the session data with the string keys Rails serializes.
+type Session map[string]any
+
+// ID returns the public session id, or an empty string for a new session.
+func (s Session) ID() string {
+	id, _ := s[idKey].(string)
+	return id
+}
+
+func (s Session) SetID(id string) {
+	s[idKey] = id
+}
+
+// CSRFToken returns the token protect_from_forgery stores in the session.
+func (s Session) CSRFToken() string {
+	token, _ := s[csrfTokenKey].(string)
+	return token
+}
+
+func (s Session) SetCSRFToken(token string) {
+	s[csrfTokenKey] = token
+}
+
+// Flash returns the flash messages which survive to the next request.
+func (s Session) Flash() map[string]any {
+	flash, _ := s[flashKey].(map[string]any)
+	flashes, _ := flash["flashes"].(map[string]any)
+	return flashes
+}
+
+// SetFlash adds a flash message for the next request, like flash[key] =.
+// Like Rails, key is no longer discarded, and other discarded keys are kept.
+func (s Session) SetFlash(key string, value any) {
+	flashes := s.Flash()
+	if flashes == nil {
+		flashes = map[string]any{}
+	}
+	flashes[key] = value
+
+	flash, _ := s[flashKey].(map[string]any)
+	discarded, _ := flash["discard"].([]any)
+
+	discard := []any{}
+	for _, k := range discarded {
+		if k != key {
+			discard = append(discard, k)
+		}
+	}
+
+	s[flashKey] = map[string]any{"discard": discard, "flashes": flashes}
+}
+
+// WardenUser returns the record key and authenticatable salt Devise stores
+// for scope.
+func (s Session) WardenUser(scope string) (key []any, salt string, ok bool) {
+	value, _ := s[wardenKey(scope)].([]any)
+	if len(value) != 2 {
+		return nil, "", false
+	}
+
+	key, ok = value[0].([]any)
+	salt, _ = value[1].(string)
+	return key, salt, ok
+}
+
+func (s Session) SetWardenUser(scope string, key []any, salt string) {
+	s[wardenKey(scope)] = []any{key, salt}
+}
+
+func (s Session) DeleteWardenUser(scope string) {
+	delete(s, wardenKey(scope))
+}
+
+func wardenKey(scope string) string {
+	return "warden.user." + scope + ".key"
+}
+
+// Store reads and writes the session through the encrypted cookie jar, like
+// CookieStore does with a secret_key_base.
+type Store struct {
+	jar *cookies.Jar
+	key string
+}
+
+// New returns a store for the session cookie named key, which is
+// "_<app name>_session" by default in Rails.
+func New(jar *cookies.Jar, key string) *Store {
+	if jar == nil {
+		panic("session: empty cookie jar")
+	}
+	if key == "" {
+		panic("session: empty key")
+	}
+
+	return &Store{
+		jar: jar,
+		key: key,
+	}
+}
+
+// Load decrypts the session cookie. A nil cookie gives an empty session.
+func (s *Store) Load(cookie *http.Cookie) (Session, error) {
+	session := Session{}
+	if cookie == nil {
+		return session, nil
+	}
+
+	if err := s.jar.ReadEncrypted(cookie, &session); err != nil {
+		return nil, err
+	}
+	if session == nil {
+		session = Session{}
+	}
+
+	return session, nil
+}
+
+// Dump encrypts session into a new session cookie, generating a session id
+// when there is none. It returns cookies.CookieOverflowError when the session
+// does not fit in a cookie.
+func (s *Store) Dump(session Session) (*http.Cookie, error) {
+	if session.ID() == "" {
+		session.SetID(GenerateID())
+	}
+
+	cookie := &http.Cookie{
+		Name:     s.key,
+		Path:     "/",
+		HttpOnly: true,
+	}
+	if err := s.jar.WriteEncrypted(cookie, session); err != nil {
+		return nil, err
+	}
+
+	return cookie, nil
+}
+
+// GenerateID returns a new public session id, like SecureRandom.hex(16).
+func GenerateID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// GenerateCSRFToken returns a new session CSRF token, like
+// SecureRandom.urlsafe_base64(32).
+func GenerateCSRFToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,94 @@
+package session
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/atitan/activesupport-go/actiondispatch/cookies"
+	"github.com/google/go-cmp/cmp"
+)
+
+var jar = cookies.New(cookies.DefaultConfig([]byte("b3c631c314c0bbca50c1b2843150fe33")))
+
+func TestRoundTrip(t *testing.T) {
+	store := New(jar, "_app_session")
+
+	session, err := store.Load(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	session.SetCSRFToken(GenerateCSRFToken())
+	session.SetFlash("notice", "Signed in")
+	session.SetWardenUser("user", []any{float64(1)}, "$2a$12$abcdefghijklmnopqrstuv")
+	session["cart"] = []any{"apple"}
+
+	cookie, err := store.Dump(session)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if cookie.Name != "_app_session" || cookie.Path != "/" || !cookie.HttpOnly {
+		t.Errorf("unexpected cookie: %v", cookie)
+	}
+	if len(session.ID()) != 32 {
+		t.Errorf("unexpected session id: %q", session.ID())
+	}
+
+	loaded, err := store.Load(cookie)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(session, loaded); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+
+	if loaded.Flash()["notice"] != "Signed in" {
+		t.Errorf("flash mismatch: %v", loaded.Flash())
+	}
+	key, salt, ok := loaded.WardenUser("user")
+	if !ok || cmp.Diff([]any{float64(1)}, key) != "" || salt != "$2a$12$abcdefghijklmnopqrstuv" {
+		t.Errorf("warden user mismatch: %v, %q, %v", key, salt, ok)
+	}
+}
+
+func TestSetFlashDiscard(t *testing.T) {
+	session := Session{flashKey: map[string]any{
+		"discard": []any{"alert", "notice"},
+		"flashes": map[string]any{"alert": "Oops", "notice": "Old"},
+	}}
+
+	session.SetFlash("notice", "Signed in")
+
+	want := map[string]any{
+		"discard": []any{"alert"},
+		"flashes": map[string]any{"alert": "Oops", "notice": "Signed in"},
+	}
+	if diff := cmp.Diff(want, session[flashKey]); diff != "" {
+		t.Errorf("flash mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestLoadWrongName(t *testing.T) {
+	cookie, err := New(jar, "_app_session").Dump(Session{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	cookie.Name = "_other_session"
+	if _, err := New(jar, "_other_session").Load(cookie); err == nil {
+		t.Error("expected error for cookie of another session key")
+	}
+}
+
+func TestCookieOverflow(t *testing.T) {
+	session := Session{"big": strings.Repeat("a", cookies.MaxCookieSize)}
+
+	if _, err := New(jar, "_app_session").Dump(session); !errors.Is(err, cookies.CookieOverflowError) {
+		t.Errorf("expected CookieOverflowError, got: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/atitan/activesupport-go/message/encryptor"
+)
+
+const ivLength = 12
+
+// encryptAES256GCM mirrors ActiveRecord::Encryption::Cipher::Aes256Gcm, which
+// stores the IV and the authentication tag in headers instead of the payload.
+// Deterministic encryption derives the IV from the clear text, so equal
+// values give equal ciphertexts.
+func encryptAES256GCM(key Key, clearText []byte, deterministic bool, random io.Reader) (*Message, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, ivLength)
+	if deterministic {
+		mac := hmac.New(sha256.New, key.Secret)
+		mac.Write(clearText)
+		copy(iv, mac.Sum(nil))
+	} else if _, err := io.ReadFull(random, iv); err != nil {
+		return nil, err
+	}
+
+	sealed := aead.Seal(nil, iv, clearText, nil)
+	tagStart := len(sealed) - encryptor.GCMTagSize
+
+	return &Message{
+		Payload: sealed[:tagStart],
+		Headers: Headers{
+			IV:      iv,
+			AuthTag: sealed[tagStart:],
+		},
+	}, nil
+}
+
+// encryptMessage mirrors ActiveRecord::Encryption::Cipher, which marks binary
+// clear text with the e header so Ruby restores its encoding.
+func encryptMessage(key Key, clearText []byte, binary, deterministic bool, random io.Reader) (*Message, error) {
+	m, err := encryptAES256GCM(key, clearText, deterministic, random)
+	if err != nil {
+		return nil, err
+	}
+
+	if binary {
+		m.Headers.Encoding = binaryEncoding
+	}
+
+	return m, nil
+}
+
+// decryptWithEach tries keys in order, like Cipher#decrypt with several keys.
+func decryptWithEach(m *Message, keys []Key) ([]byte, error) {
+	for _, key := range keys {
+		if clearText, err := decryptAES256GCM(key, m); err == nil {
+			return clearText, nil
+		}
+	}
+
+	return nil, DecryptionError
+}
+
+func decryptAES256GCM(key Key, m *Message) ([]byte, error) {
+	if len(m.Headers.AuthTag) != encryptor.GCMTagSize {
+		return nil, fmt.Errorf("%w: invalid auth tag", DecryptionError)
+	}
+	if len(m.Headers.IV) != ivLength {
+		return nil, fmt.Errorf("%w: invalid iv", DecryptionError)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := append(append([]byte{}, m.Payload...), m.Headers.AuthTag...)
+	clearText, err := aead.Open(nil, m.Headers.IV, sealed, nil)
+	if err != nil {
+		return nil, DecryptionError
+	}
+
+	return clearText, nil
+}
+
+func newAEAD(key Key) (cipher.AEAD, error) {
+	if len(key.Secret) != KeyLength {
+		return nil, fmt.Errorf("%w: key must be %d bytes", ConfigurationError, KeyLength)
+	}
+
+	block, err := aes.NewCipher(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
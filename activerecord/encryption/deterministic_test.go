@@ -0,0 +1,129 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func deterministicConfig() Config {
+	config := testConfig()
+	config.DeterministicKey = "0yxGVmmJYHdGKpUuPpkt0k1iDcOHaGwi"
+
+	return config
+}
+
+func TestDeterministic(t *testing.T) {
+	e := New(deterministicConfig()).Deterministic()
+
+	first, err := e.Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	second, err := New(deterministicConfig()).Deterministic().Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if first != second {
+		t.Errorf("output mismatch: %s, %s", first, second)
+	}
+
+	m, err := UnmarshalMessage([]byte(first))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	key := DeriveKey("0yxGVmmJYHdGKpUuPpkt0k1iDcOHaGwi", "xEY0dt6TZcAMg52K7O84wYzkjvbA62Hz", sha256.New)
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte("john@example.com"))
+	if !hmac.Equal(m.Headers.IV, mac.Sum(nil)[:12]) {
+		t.Errorf("unexpected iv: %x", m.Headers.IV)
+	}
+
+	if decrypted, err := e.Decrypt(first); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+	if _, err := New(deterministicConfig()).Decrypt(first); !errors.Is(err, DecryptionError) {
+		t.Errorf("expected DecryptionError, got: %v", err)
+	}
+}
+
+func TestDeterministicDowncase(t *testing.T) {
+	e := New(deterministicConfig()).Deterministic().WithDowncase()
+
+	first, err := e.Encrypt("John@Example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	second, err := e.Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if first != second {
+		t.Errorf("output mismatch: %s, %s", first, second)
+	}
+
+	if decrypted, err := e.Decrypt(first); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+}
+
+func TestDeterministicRails(t *testing.T) {
+	want, err := os.ReadFile("testdata/TestDeterministicRails.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	e := New(deterministicConfig()).Deterministic()
+	if decrypted, err := e.Decrypt(string(want)); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+
+	encrypted, err := e.Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if encrypted != string(want) {
+		t.Errorf("output mismatch: %s, %s", encrypted, want)
+	}
+}
+
+func TestDeterministicCompression(t *testing.T) {
+	clearText := strings.Repeat("long text ", 20)
+
+	if _, err := New(deterministicConfig()).Deterministic().Encrypt(clearText); !errors.Is(err, DeterministicCompressionError) {
+		t.Errorf("expected DeterministicCompressionError, got: %v", err)
+	}
+
+	config := deterministicConfig()
+	config.Compress = false
+	e := New(config).Deterministic()
+
+	encrypted, err := e.Encrypt(clearText)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decrypted, err := e.Decrypt(encrypted); err != nil || decrypted != clearText {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+}
+
+func TestDeterministicWithoutKey(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+
+	New(testConfig()).Deterministic()
+}
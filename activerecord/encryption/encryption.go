@@ -0,0 +1,302 @@
+// Package encryption reads and writes the attributes Rails encrypts with
+// ActiveRecord::Encryption, which are stored as JSON messages like
+// {"p":"...","h":{"iv":"...","at":"..."}}.
+package encryption
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// compressionThreshold is the size above which Rails compresses clear text.
+const compressionThreshold = 140
+
+// binaryEncoding is the Ruby encoding of clear text which is not UTF-8.
+const binaryEncoding = "ASCII-8BIT"
+
+var (
+	DecryptionError    = errors.New("encryption: decryption failed")
+	EncodingError      = errors.New("encryption: invalid message encoding")
+	ConfigurationError = errors.New("encryption: invalid configuration")
+
+	// DeterministicCompressionError is returned by deterministic encryptors
+	// for clear text Rails would compress, as compress/zlib does not give the
+	// same output as Ruby's zlib.
+	DeterministicCompressionError = errors.New("encryption: deterministic clear text too long to compress")
+)
+
+// Config mirrors config.active_record.encryption.
+type Config struct {
+	// PrimaryKeys are the primary_key passwords. The last one encrypts, all of
+	// them decrypt.
+	PrimaryKeys []string
+	// DeterministicKey encrypts deterministic attributes. It cannot be rotated.
+	DeterministicKey  string
+	KeyDerivationSalt string
+
+	// HashDigest derives keys, sha256.New since Rails 7.1 and sha1.New before.
+	HashDigest func() hash.Hash
+	// SupportSHA1ForNonDeterministicEncryption also decrypts with keys derived
+	// with SHA1, while migrating to SHA256.
+	SupportSHA1ForNonDeterministicEncryption bool
+
+	// StoreKeyReferences references the encrypting key in the i header, so
+	// decryption does not need to try every key.
+	StoreKeyReferences bool
+	Compress           bool
+
+	// SupportUnencryptedData reads values which fail to decrypt as clear
+	// text, while existing columns are backfilled.
+	SupportUnencryptedData bool
+}
+
+// DefaultConfig returns the encryption config of a Rails 7.1+ application,
+// without any keys.
+func DefaultConfig() Config {
+	return Config{
+		HashDigest: sha256.New,
+		Compress:   true,
+	}
+}
+
+// Encryptor mirrors ActiveRecord::Encryption::Encryptor with the key
+// provider of an attribute.
+type Encryptor struct {
+	keyProvider KeyProvider
+	// sha1KeyProvider decrypts with keys derived with SHA1 when
+	// SupportSHA1ForNonDeterministicEncryption is set.
+	sha1KeyProvider          KeyProvider
+	deterministicKeyProvider KeyProvider
+	deterministic            bool
+	downcase                 bool
+	compress                 bool
+	random                   io.Reader
+
+	previous               []*Encryptor
+	supportUnencryptedData bool
+}
+
+// New derives the keys of config, which is slow by design, so an Encryptor
+// should be built once and reused. It encrypts with the primary keys.
+func New(config Config) *Encryptor {
+	if len(config.PrimaryKeys) == 0 {
+		panic("encryption: empty primary key")
+	}
+
+	e := &Encryptor{
+		keyProvider: NewDerivedSecretKeyProvider(config, config.PrimaryKeys...),
+		compress:    config.Compress,
+		random:      rand.Reader,
+
+		supportUnencryptedData: config.SupportUnencryptedData,
+	}
+
+	if config.SupportSHA1ForNonDeterministicEncryption {
+		sha1Config := config
+		sha1Config.HashDigest = sha1.New
+		e.sha1KeyProvider = NewDerivedSecretKeyProvider(sha1Config, config.PrimaryKeys...)
+	}
+	if config.DeterministicKey != "" {
+		e.deterministicKeyProvider = NewDeterministicKeyProvider(config)
+	}
+
+	return e
+}
+
+// KeyProvider returns the key provider of the encryptor, which is the
+// primary key provider unless replaced.
+func (e *Encryptor) KeyProvider() KeyProvider {
+	return e.keyProvider
+}
+
+// WithKeyProvider returns a copy of the encryptor which encrypts and decrypts
+// with keys from p, like the key_provider option of encrypts.
+func (e *Encryptor) WithKeyProvider(p KeyProvider) *Encryptor {
+	copied := *e
+	copied.keyProvider = p
+	copied.sha1KeyProvider = nil
+
+	return &copied
+}
+
+// Deterministic returns a copy of the encryptor for attributes declared with
+// deterministic: true. It encrypts and decrypts with the deterministic key,
+// and gives the same ciphertext as Rails for the same clear text, so it can
+// be used in queries. Unless compression is disabled, clear text longer than
+// 140 bytes fails with DeterministicCompressionError instead of giving a
+// ciphertext different from the one of Rails.
+func (e *Encryptor) Deterministic() *Encryptor {
+	if e.deterministicKeyProvider == nil {
+		panic("encryption: empty deterministic key")
+	}
+
+	copied := e.WithKeyProvider(e.deterministicKeyProvider)
+	copied.deterministic = true
+
+	return copied
+}
+
+// WithDowncase returns a copy of the encryptor which downcases clear text
+// before encrypting it, like downcase: true or ignore_case: true. With
+// ignore_case, Rails keeps the original value in the original_<attribute>
+// column, which is left to the caller.
+func (e *Encryptor) WithDowncase() *Encryptor {
+	copied := *e
+	copied.downcase = true
+
+	return &copied
+}
+
+// WithPrevious returns a copy of the encryptor which decrypts with the
+// previous schemes, in order, when its own keys fail, like the previous:
+// option of encrypts. Previous schemes never encrypt.
+func (e *Encryptor) WithPrevious(previous ...*Encryptor) *Encryptor {
+	copied := *e
+	copied.previous = previous
+
+	return &copied
+}
+
+// WithRand returns a copy of the encryptor which reads IVs from r.
+func (e *Encryptor) WithRand(r io.Reader) *Encryptor {
+	copied := *e
+	copied.random = r
+
+	return &copied
+}
+
+// Encrypt encrypts clearText into a serialized message. Clear text which is
+// not valid UTF-8 is marked as binary for Ruby.
+func (e *Encryptor) Encrypt(clearText string) (string, error) {
+	if e.downcase {
+		clearText = strings.ToLower(clearText)
+	}
+
+	if e.deterministic && e.compress && len(clearText) > compressionThreshold {
+		return "", fmt.Errorf("%w: %d bytes", DeterministicCompressionError, len(clearText))
+	}
+
+	data, compressed, err := e.compressIfWorthIt([]byte(clearText))
+	if err != nil {
+		return "", err
+	}
+
+	key, err := e.keyProvider.EncryptionKey()
+	if err != nil {
+		return "", err
+	}
+
+	m, err := encryptMessage(key, data, !utf8.ValidString(clearText), e.deterministic, e.random)
+	if err != nil {
+		return "", err
+	}
+
+	m.Headers.EncryptedDataKey = key.PublicTags.EncryptedDataKey
+	m.Headers.KeyID = key.PublicTags.KeyID
+	m.Headers.Compressed = compressed
+
+	serialized, err := MarshalMessage(m)
+	if err != nil {
+		return "", err
+	}
+
+	return string(serialized), nil
+}
+
+// Decrypt decrypts a serialized message, trying every key of the config, then
+// the previous schemes. With SupportUnencryptedData, a value none of them
+// decrypt is returned as is.
+func (e *Encryptor) Decrypt(encryptedText string) (string, error) {
+	clearText, err := e.decrypt(encryptedText)
+	for _, previous := range e.previous {
+		if err == nil {
+			break
+		}
+		clearText, err = previous.decrypt(encryptedText)
+	}
+
+	if err != nil && e.supportUnencryptedData && errors.Is(err, DecryptionError) {
+		return encryptedText, nil
+	}
+
+	return clearText, err
+}
+
+// Reencrypt decrypts a stored value with any scheme, and encrypts it again
+// with the current one, like record.encrypt in a backfill.
+func (e *Encryptor) Reencrypt(value string) (string, error) {
+	clearText, err := e.Decrypt(value)
+	if err != nil {
+		return "", err
+	}
+
+	return e.Encrypt(clearText)
+}
+
+func (e *Encryptor) decrypt(encryptedText string) (string, error) {
+	m, err := UnmarshalMessage([]byte(encryptedText))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", DecryptionError, err)
+	}
+
+	data, err := e.decryptMessage(m, e.keyProvider)
+	if err != nil && e.sha1KeyProvider != nil {
+		data, err = e.decryptMessage(m, e.sha1KeyProvider)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if m.Headers.Compressed {
+		if data, err = uncompress(data); err != nil {
+			return "", fmt.Errorf("%w: %w", DecryptionError, err)
+		}
+	}
+
+	return string(data), nil
+}
+
+func (e *Encryptor) decryptMessage(m *Message, p KeyProvider) ([]byte, error) {
+	keys, err := p.DecryptionKeys(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return decryptWithEach(m, keys)
+}
+
+func (e *Encryptor) compressIfWorthIt(data []byte) ([]byte, bool, error) {
+	if !e.compress || len(data) <= compressionThreshold {
+		return data, false, nil
+	}
+
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, false, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, false, err
+	}
+
+	return b.Bytes(), true, nil
+}
+
+func uncompress(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,218 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func testConfig() Config {
+	config := DefaultConfig()
+	config.PrimaryKeys = []string{"EGY8WhulUOXixybod7ZWwMIL68R9o5kC"}
+	config.KeyDerivationSalt = "xEY0dt6TZcAMg52K7O84wYzkjvbA62Hz"
+
+	return config
+}
+
+func TestRoundTrip(t *testing.T) {
+	e := New(testConfig())
+
+	for _, clearText := range []string{"", "john@example.com", strings.Repeat("long text ", 20), "\xff\xfe"} {
+		encrypted, err := e.Encrypt(clearText)
+		if err != nil {
+			t.Errorf("input: %q; %v", clearText, err)
+			continue
+		}
+
+		decrypted, err := e.Decrypt(encrypted)
+		if err != nil {
+			t.Errorf("input: %q; %v", clearText, err)
+			continue
+		}
+		if decrypted != clearText {
+			t.Errorf("data mismatch: %q, %q", decrypted, clearText)
+		}
+	}
+}
+
+func TestEncryptFormat(t *testing.T) {
+	e := New(testConfig()).WithRand(bytes.NewReader(make([]byte, 12)))
+
+	encrypted, err := e.Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	m, err := UnmarshalMessage([]byte(encrypted))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(m.Payload) != len("john@example.com") || len(m.Headers.AuthTag) != 16 || m.Headers.Compressed {
+		t.Errorf("unexpected message: %+v", m)
+	}
+
+	prefix := `{"p":"`
+	suffix := `","h":{"iv":"AAAAAAAAAAAAAAAA","at":"` + encodedTag(t, m) + `"}}`
+	if !strings.HasPrefix(encrypted, prefix) || !strings.HasSuffix(encrypted, suffix) {
+		t.Errorf("unexpected format: %s", encrypted)
+	}
+}
+
+func encodedTag(t *testing.T, m *Message) string {
+	serialized, err := MarshalMessage(&Message{Headers: Headers{AuthTag: m.Headers.AuthTag}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(string(serialized), `{"p":"","h":{"at":"`), `"}}`)
+}
+
+func TestEncryptHeaders(t *testing.T) {
+	e := New(testConfig())
+
+	encrypted, err := e.Encrypt(strings.Repeat("a", compressionThreshold+1))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.HasSuffix(encrypted, `,"c":true}}`) {
+		t.Errorf("compression header missing: %s", encrypted)
+	}
+
+	encrypted, err = e.Encrypt("\xff")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.Contains(encrypted, `"e":"QVNDSUktOEJJVA=="`) {
+		t.Errorf("encoding header missing: %s", encrypted)
+	}
+
+	config := testConfig()
+	config.Compress = false
+	encrypted, err = New(config).Encrypt(strings.Repeat("a", compressionThreshold+1))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if strings.Contains(encrypted, `"c"`) {
+		t.Errorf("unexpected compression: %s", encrypted)
+	}
+}
+
+func TestDecryptRotatedKey(t *testing.T) {
+	old := New(testConfig())
+	encrypted, err := old.Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	config := testConfig()
+	config.PrimaryKeys = append(config.PrimaryKeys, "new primary key")
+	e := New(config)
+
+	if decrypted, err := e.Decrypt(encrypted); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+
+	reencrypted, err := e.Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := old.Decrypt(reencrypted); !errors.Is(err, DecryptionError) {
+		t.Errorf("expected DecryptionError, got: %v", err)
+	}
+}
+
+func TestDecryptSHA1(t *testing.T) {
+	config := testConfig()
+	config.HashDigest = sha1.New
+	encrypted, err := New(config).Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := New(testConfig()).Decrypt(encrypted); !errors.Is(err, DecryptionError) {
+		t.Errorf("expected DecryptionError, got: %v", err)
+	}
+
+	config = testConfig()
+	config.SupportSHA1ForNonDeterministicEncryption = true
+	if decrypted, err := New(config).Decrypt(encrypted); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+}
+
+func TestDecryptRails(t *testing.T) {
+	e := New(testConfig())
+	envelope := e.WithKeyProvider(NewEnvelopeEncryptionKeyProvider(e.KeyProvider()))
+
+	for name, tc := range map[string]struct {
+		e         *Encryptor
+		clearText string
+		check     func(m *Message) bool
+	}{
+		"TestDecryptRails": {
+			e, "john@example.com", func(m *Message) bool { return m.Headers.Encoding == "" },
+		},
+		"TestDecryptRailsCompressed": {
+			e, strings.Repeat("long text ", 20), func(m *Message) bool { return m.Headers.Compressed },
+		},
+		"TestDecryptRailsBinary": {
+			e, "\xff\xfe", func(m *Message) bool { return m.Headers.Encoding == binaryEncoding },
+		},
+		"TestDecryptRailsKeyReference": {
+			e, "john@example.com", func(m *Message) bool { return m.Headers.KeyID != "" },
+		},
+		"TestDecryptRailsEnvelope": {
+			envelope, "john@example.com", func(m *Message) bool { return m.Headers.EncryptedDataKey != nil },
+		},
+	} {
+		encrypted, err := os.ReadFile("testdata/" + name + ".txt")
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		m, err := UnmarshalMessage(encrypted)
+		if err != nil || !tc.check(m) {
+			t.Errorf("%s: unexpected message: %s, %v", name, encrypted, err)
+		}
+
+		if decrypted, err := tc.e.Decrypt(string(encrypted)); err != nil || decrypted != tc.clearText {
+			t.Errorf("%s: unexpected result: %q, %v", name, decrypted, err)
+		}
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	e := New(testConfig())
+
+	for input, want := range map[string]error{
+		"john@example.com":                                      EncodingError,
+		`"john@example.com"`:                                    EncodingError,
+		`{"p":"AA==","h":{"iv":"!"}}`:                           EncodingError,
+		`{"p":"AA=="}`:                                          DecryptionError,
+		`{"p":"AA==","h":{"at":"AA=="}}`:                        DecryptionError,
+		`{"p":"","h":{"k":{"p":"","h":{"k":{"p":"","h":{}}}}}}`: DecryptionError,
+	} {
+		_, err := e.Decrypt(input)
+		if !errors.Is(err, want) || !errors.Is(err, DecryptionError) {
+			t.Errorf("input: %s; expected %v, got: %v", input, want, err)
+		}
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	if id := (Key{Secret: []byte("secret")}).ID(); id != "e5e9" {
+		t.Errorf("unexpected id: %q", id)
+	}
+}
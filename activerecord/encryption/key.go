@@ -0,0 +1,39 @@
+package encryption
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"hash"
+
+	"github.com/atitan/activesupport-go/keygenerator"
+)
+
+const (
+	// KeyLength is the secret length of aes-256-gcm.
+	KeyLength = 32
+	// KeyIterations is the PBKDF2 iteration count of ActiveSupport::KeyGenerator,
+	// which ActiveRecord::Encryption uses with its default.
+	KeyIterations = 1 << 16
+)
+
+// Key is a secret which encrypts attributes. Its public tags are added to
+// the headers of the messages it encrypts.
+type Key struct {
+	Secret     []byte
+	PublicTags Headers
+}
+
+// DeriveKey derives a key from a password like
+// ActiveRecord::Encryption::KeyGenerator#derive_key_from. digest is the
+// hash_digest_class of the encryption config.
+func DeriveKey(password, keyDerivationSalt string, digest func() hash.Hash) Key {
+	g := keygenerator.New([]byte(password), KeyIterations, digest)
+
+	return Key{Secret: g.GenerateKey([]byte(keyDerivationSalt), KeyLength)}
+}
+
+// ID returns the reference stored in the i header with store_key_references.
+func (k Key) ID() string {
+	sum := sha1.Sum(k.Secret)
+	return hex.EncodeToString(sum[:])[:4]
+}
@@ -0,0 +1,158 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+// KeyProvider mirrors the key providers of ActiveRecord::Encryption, which
+// pick the key encrypting a new message and the keys which may decrypt a
+// stored one.
+type KeyProvider interface {
+	EncryptionKey() (Key, error)
+	DecryptionKeys(m *Message) ([]Key, error)
+}
+
+// StaticKeyProvider mirrors ActiveRecord::Encryption::KeyProvider. The last
+// key encrypts, and all of them decrypt, unless a message references its key
+// in the i header, in which case only the keys with that ID decrypt.
+type StaticKeyProvider struct {
+	keys               []Key
+	storeKeyReferences bool
+}
+
+// NewKeyProvider returns a provider of keys. With storeKeyReferences, new
+// messages reference their key in the i header, like store_key_references.
+func NewKeyProvider(keys []Key, storeKeyReferences bool) *StaticKeyProvider {
+	if len(keys) == 0 {
+		panic("encryption: empty keys")
+	}
+
+	return &StaticKeyProvider{
+		keys:               keys,
+		storeKeyReferences: storeKeyReferences,
+	}
+}
+
+// NewDerivedSecretKeyProvider derives keys from passwords with the salt and
+// digest of config, like ActiveRecord::Encryption::DerivedSecretKeyProvider.
+func NewDerivedSecretKeyProvider(config Config, passwords ...string) *StaticKeyProvider {
+	if config.KeyDerivationSalt == "" {
+		panic("encryption: empty key derivation salt")
+	}
+	if config.HashDigest == nil {
+		panic("encryption: empty hash digest")
+	}
+
+	keys := make([]Key, len(passwords))
+	for i, password := range passwords {
+		keys[i] = DeriveKey(password, config.KeyDerivationSalt, config.HashDigest)
+	}
+
+	return NewKeyProvider(keys, config.StoreKeyReferences)
+}
+
+// NewDeterministicKeyProvider derives the key of config.DeterministicKey,
+// like ActiveRecord::Encryption::DeterministicKeyProvider.
+func NewDeterministicKeyProvider(config Config) *StaticKeyProvider {
+	if config.DeterministicKey == "" {
+		panic("encryption: empty deterministic key")
+	}
+
+	return NewDerivedSecretKeyProvider(config, config.DeterministicKey)
+}
+
+func (p *StaticKeyProvider) EncryptionKey() (Key, error) {
+	key := p.keys[len(p.keys)-1]
+	if p.storeKeyReferences {
+		key.PublicTags.KeyID = key.ID()
+	}
+
+	return key, nil
+}
+
+func (p *StaticKeyProvider) DecryptionKeys(m *Message) ([]Key, error) {
+	if m.Headers.KeyID == "" {
+		return p.keys, nil
+	}
+
+	var keys []Key
+	for _, key := range p.keys {
+		if key.ID() == m.Headers.KeyID {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// EnvelopeEncryptionKeyProvider mirrors
+// ActiveRecord::Encryption::EnvelopeEncryptionKeyProvider. Every message is
+// encrypted with a random data key, which is itself encrypted with the
+// primary key and stored in the k header.
+type EnvelopeEncryptionKeyProvider struct {
+	primary KeyProvider
+	random  io.Reader
+}
+
+func NewEnvelopeEncryptionKeyProvider(primary KeyProvider) *EnvelopeEncryptionKeyProvider {
+	if primary == nil {
+		panic("encryption: empty primary key provider")
+	}
+
+	return &EnvelopeEncryptionKeyProvider{
+		primary: primary,
+		random:  rand.Reader,
+	}
+}
+
+// WithRand returns a copy of the provider which reads data keys and their
+// IVs from r.
+func (p *EnvelopeEncryptionKeyProvider) WithRand(r io.Reader) *EnvelopeEncryptionKeyProvider {
+	copied := *p
+	copied.random = r
+
+	return &copied
+}
+
+func (p *EnvelopeEncryptionKeyProvider) EncryptionKey() (Key, error) {
+	primaryKey, err := p.primary.EncryptionKey()
+	if err != nil {
+		return Key{}, err
+	}
+
+	secret := make([]byte, KeyLength)
+	if _, err := io.ReadFull(p.random, secret); err != nil {
+		return Key{}, err
+	}
+
+	encryptedDataKey, err := encryptMessage(primaryKey, secret, true, false, p.random)
+	if err != nil {
+		return Key{}, err
+	}
+
+	key := Key{Secret: secret}
+	key.PublicTags.EncryptedDataKey = encryptedDataKey
+	key.PublicTags.KeyID = primaryKey.PublicTags.KeyID
+
+	return key, nil
+}
+
+func (p *EnvelopeEncryptionKeyProvider) DecryptionKeys(m *Message) ([]Key, error) {
+	if m.Headers.EncryptedDataKey == nil {
+		return nil, fmt.Errorf("%w: missing encrypted data key", DecryptionError)
+	}
+
+	primaryKeys, err := p.primary.DecryptionKeys(m)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := decryptWithEach(m.Headers.EncryptedDataKey, primaryKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return []Key{{Secret: secret}}, nil
+}
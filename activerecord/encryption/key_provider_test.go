@@ -0,0 +1,96 @@
+package encryption
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStoreKeyReferences(t *testing.T) {
+	config := testConfig()
+	config.PrimaryKeys = append(config.PrimaryKeys, "new primary key")
+	config.StoreKeyReferences = true
+	e := New(config)
+
+	encrypted, err := e.Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	m, err := UnmarshalMessage([]byte(encrypted))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	key, _ := e.KeyProvider().EncryptionKey()
+	if m.Headers.KeyID == "" || m.Headers.KeyID != key.ID() {
+		t.Errorf("unexpected key id: %q, %q", m.Headers.KeyID, key.ID())
+	}
+
+	keys, err := e.KeyProvider().DecryptionKeys(m)
+	if err != nil || len(keys) != 1 || keys[0].ID() != key.ID() {
+		t.Errorf("unexpected keys: %v, %v", keys, err)
+	}
+
+	if decrypted, err := e.Decrypt(encrypted); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+
+	m.Headers.KeyID = "0000"
+	if keys, _ := e.KeyProvider().DecryptionKeys(m); len(keys) != 0 {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestEnvelopeEncryption(t *testing.T) {
+	config := testConfig()
+	config.StoreKeyReferences = true
+	e := New(config)
+	e = e.WithKeyProvider(NewEnvelopeEncryptionKeyProvider(e.KeyProvider()))
+
+	encrypted, err := e.Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	m, err := UnmarshalMessage([]byte(encrypted))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	dataKey := m.Headers.EncryptedDataKey
+	if dataKey == nil || len(dataKey.Payload) != KeyLength || dataKey.Headers.Encoding != binaryEncoding {
+		t.Errorf("unexpected data key: %+v", dataKey)
+	}
+	if m.Headers.KeyID != DeriveKey(config.PrimaryKeys[0], config.KeyDerivationSalt, config.HashDigest).ID() {
+		t.Errorf("unexpected key id: %q", m.Headers.KeyID)
+	}
+
+	if decrypted, err := e.Decrypt(encrypted); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+
+	config.PrimaryKeys = append(config.PrimaryKeys, "new primary key")
+	rotated := New(config)
+	rotated = rotated.WithKeyProvider(NewEnvelopeEncryptionKeyProvider(rotated.KeyProvider()))
+	if decrypted, err := rotated.Decrypt(encrypted); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+
+	if _, err := New(testConfig()).Decrypt(encrypted); !errors.Is(err, DecryptionError) {
+		t.Errorf("expected DecryptionError, got: %v", err)
+	}
+
+	m.Headers.EncryptedDataKey = nil
+	withoutDataKey, err := MarshalMessage(m)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := e.Decrypt(string(withoutDataKey)); !errors.Is(err, DecryptionError) {
+		t.Errorf("expected DecryptionError, got: %v", err)
+	}
+}
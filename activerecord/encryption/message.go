@@ -0,0 +1,169 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// maxMessageLevel allows one nested message, the encrypted data key of
+// envelope encryption.
+const maxMessageLevel = 2
+
+// Message is the JSON document an encrypted attribute is stored as.
+type Message struct {
+	Payload []byte
+	Headers Headers
+}
+
+// Headers are the known properties of a message, serialized under their
+// one or two letter keys.
+type Headers struct {
+	IV               []byte   // iv
+	AuthTag          []byte   // at
+	Encoding         string   // e
+	EncryptedDataKey *Message // k
+	KeyID            string   // i
+	Compressed       bool     // c
+}
+
+// MarshalMessage serializes m like ActiveRecord::Encryption::MessageSerializer,
+// with the headers in the order Rails adds them.
+func MarshalMessage(m *Message) ([]byte, error) {
+	var b bytes.Buffer
+	if err := writeMessage(&b, m); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+func writeMessage(b *bytes.Buffer, m *Message) error {
+	b.WriteString(`{"p":`)
+	writeBinary(b, m.Payload)
+	b.WriteString(`,"h":{`)
+
+	first := true
+	key := func(name string) {
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		fmt.Fprintf(b, "%q:", name)
+	}
+
+	if m.Headers.IV != nil {
+		key("iv")
+		writeBinary(b, m.Headers.IV)
+	}
+	if m.Headers.AuthTag != nil {
+		key("at")
+		writeBinary(b, m.Headers.AuthTag)
+	}
+	if m.Headers.Encoding != "" {
+		key("e")
+		writeBinary(b, []byte(m.Headers.Encoding))
+	}
+	if m.Headers.EncryptedDataKey != nil {
+		key("k")
+		if err := writeMessage(b, m.Headers.EncryptedDataKey); err != nil {
+			return err
+		}
+	}
+	if m.Headers.KeyID != "" {
+		key("i")
+		writeBinary(b, []byte(m.Headers.KeyID))
+	}
+	if m.Headers.Compressed {
+		key("c")
+		b.WriteString("true")
+	}
+
+	b.WriteString("}}")
+	return nil
+}
+
+// Every string is stored base64 encoded, as Rails treats them all as binary.
+func writeBinary(b *bytes.Buffer, data []byte) {
+	b.WriteByte('"')
+	b.WriteString(base64.StdEncoding.EncodeToString(data))
+	b.WriteByte('"')
+}
+
+// UnmarshalMessage parses a serialized message. It returns EncodingError for
+// anything which is not a message, like a plain text value.
+func UnmarshalMessage(data []byte) (*Message, error) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", EncodingError, err)
+	}
+
+	return parseMessage(raw, 1)
+}
+
+func parseMessage(raw any, level int) (*Message, error) {
+	if level > maxMessageLevel {
+		return nil, fmt.Errorf("%w: more than one level of hash nesting in headers is not supported", DecryptionError)
+	}
+
+	data, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a message", EncodingError)
+	}
+
+	payload, hasPayload := data["p"]
+	headers, hasHeaders := data["h"].(map[string]any)
+	if !hasPayload || !hasHeaders {
+		return nil, fmt.Errorf("%w: invalid data format: hash without p and h keys", DecryptionError)
+	}
+
+	m := &Message{}
+
+	var err error
+	if m.Payload, err = decodeBinary(payload); err != nil {
+		return nil, err
+	}
+
+	for name, value := range headers {
+		switch name {
+		case "iv":
+			m.Headers.IV, err = decodeBinary(value)
+		case "at":
+			m.Headers.AuthTag, err = decodeBinary(value)
+		case "e":
+			m.Headers.Encoding, err = decodeString(value)
+		case "i":
+			m.Headers.KeyID, err = decodeString(value)
+		case "c":
+			m.Headers.Compressed = value == true
+		case "k":
+			m.Headers.EncryptedDataKey, err = parseMessage(value, level+1)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func decodeBinary(value any) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: expected a string, got %T", EncodingError, value)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", EncodingError, err)
+	}
+
+	return decoded, nil
+}
+
+func decodeString(value any) (string, error) {
+	decoded, err := decodeBinary(value)
+	return string(decoded), err
+}
@@ -0,0 +1,71 @@
+package encryption
+
+import (
+	"crypto/sha1"
+	"errors"
+	"testing"
+)
+
+func TestDecryptPrevious(t *testing.T) {
+	oldConfig := testConfig()
+	oldConfig.HashDigest = sha1.New
+	old := New(oldConfig)
+
+	encrypted, err := old.Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	config := testConfig()
+	config.PrimaryKeys = []string{"new primary key"}
+	e := New(config)
+	if _, err := e.Decrypt(encrypted); !errors.Is(err, DecryptionError) {
+		t.Errorf("expected DecryptionError, got: %v", err)
+	}
+
+	e = e.WithPrevious(New(deterministicConfig()), old)
+	if decrypted, err := e.Decrypt(encrypted); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+
+	reencrypted, err := e.Reencrypt(encrypted)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := old.Decrypt(reencrypted); !errors.Is(err, DecryptionError) {
+		t.Errorf("expected DecryptionError, got: %v", err)
+	}
+	if decrypted, err := New(config).Decrypt(reencrypted); err != nil || decrypted != "john@example.com" {
+		t.Errorf("unexpected result: %q, %v", decrypted, err)
+	}
+}
+
+func TestSupportUnencryptedData(t *testing.T) {
+	encrypted, err := New(testConfig()).Encrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	config := testConfig()
+	config.PrimaryKeys = []string{"new primary key"}
+	config.SupportUnencryptedData = true
+	e := New(config)
+
+	for _, value := range []string{"john@example.com", `{"p":"AA=="}`, encrypted} {
+		if decrypted, err := e.Decrypt(value); err != nil || decrypted != value {
+			t.Errorf("unexpected result: %q, %v", decrypted, err)
+		}
+	}
+
+	reencrypted, err := e.Reencrypt("john@example.com")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if decrypted, err := New(config).Decrypt(reencrypted); err != nil || decrypted != "john@example.com" || reencrypted == "john@example.com" {
+		t.Errorf("unexpected result: %q, %q, %v", reencrypted, decrypted, err)
+	}
+}
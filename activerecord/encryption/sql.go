@@ -0,0 +1,136 @@
+package encryption
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+var defaultEncryptor atomic.Pointer[Encryptor]
+
+// SetDefaultEncryptor sets the encryptor of column types without their own,
+// like the global config of ActiveRecord::Encryption.
+func SetDefaultEncryptor(e *Encryptor) {
+	defaultEncryptor.Store(e)
+}
+
+// DefaultEncryptor returns the encryptor set by SetDefaultEncryptor, or nil.
+func DefaultEncryptor() *Encryptor {
+	return defaultEncryptor.Load()
+}
+
+func encryptorOrDefault(e *Encryptor) (*Encryptor, error) {
+	if e != nil {
+		return e, nil
+	}
+
+	if e = DefaultEncryptor(); e == nil {
+		return nil, fmt.Errorf("%w: no default encryptor", ConfigurationError)
+	}
+
+	return e, nil
+}
+
+func scanEncrypted(e *Encryptor, src any) (string, bool, error) {
+	var encryptedText string
+	switch src := src.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		encryptedText = src
+	case []byte:
+		encryptedText = string(src)
+	default:
+		return "", false, fmt.Errorf("encryption: cannot scan %T", src)
+	}
+
+	e, err := encryptorOrDefault(e)
+	if err != nil {
+		return "", false, err
+	}
+
+	clearText, err := e.Decrypt(encryptedText)
+	if err != nil {
+		return "", false, err
+	}
+
+	return clearText, true, nil
+}
+
+// EncryptedString is a nullable string column declared with encrypts. It
+// decrypts on Scan and encrypts on Value with Encryptor, or the default
+// encryptor when nil, e.g. a deterministic one for queried columns.
+type EncryptedString struct {
+	String    string
+	Valid     bool
+	Encryptor *Encryptor
+}
+
+func (s *EncryptedString) Scan(src any) error {
+	clearText, valid, err := scanEncrypted(s.Encryptor, src)
+	if err != nil {
+		return err
+	}
+
+	s.String, s.Valid = clearText, valid
+
+	return nil
+}
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	e, err := encryptorOrDefault(s.Encryptor)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Encrypt(s.String)
+}
+
+// EncryptedJSON is a nullable column declared with serialize coder: JSON and
+// encrypts, which encrypts the JSON document of V.
+type EncryptedJSON[T any] struct {
+	V         T
+	Valid     bool
+	Encryptor *Encryptor
+}
+
+func (j *EncryptedJSON[T]) Scan(src any) error {
+	clearText, valid, err := scanEncrypted(j.Encryptor, src)
+	if err != nil {
+		return err
+	}
+
+	var v T
+	if valid {
+		if err := json.Unmarshal([]byte(clearText), &v); err != nil {
+			return err
+		}
+	}
+
+	j.V, j.Valid = v, valid
+
+	return nil
+}
+
+func (j EncryptedJSON[T]) Value() (driver.Value, error) {
+	if !j.Valid {
+		return nil, nil
+	}
+
+	e, err := encryptorOrDefault(j.Encryptor)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Encrypt(string(data))
+}
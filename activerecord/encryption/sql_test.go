@@ -0,0 +1,92 @@
+package encryption
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	_ sql.Scanner   = (*EncryptedString)(nil)
+	_ driver.Valuer = EncryptedString{}
+	_ sql.Scanner   = (*EncryptedJSON[any])(nil)
+	_ driver.Valuer = EncryptedJSON[any]{}
+)
+
+func TestEncryptedString(t *testing.T) {
+	e := New(testConfig())
+
+	value, err := EncryptedString{String: "john@example.com", Valid: true, Encryptor: e}.Value()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s := EncryptedString{Encryptor: e}
+	if err := s.Scan([]byte(value.(string))); err != nil || s.String != "john@example.com" || !s.Valid {
+		t.Errorf("unexpected result: %+v, %v", s, err)
+	}
+
+	if err := s.Scan(nil); err != nil || s.String != "" || s.Valid {
+		t.Errorf("unexpected result: %+v, %v", s, err)
+	}
+	if value, err := s.Value(); value != nil || err != nil {
+		t.Errorf("unexpected value: %v, %v", value, err)
+	}
+
+	if err := s.Scan(42); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestEncryptedJSON(t *testing.T) {
+	type prefs struct {
+		Theme string `json:"theme"`
+		Beta  bool   `json:"beta"`
+	}
+
+	e := New(testConfig())
+	want := prefs{Theme: "dark", Beta: true}
+
+	value, err := EncryptedJSON[prefs]{V: want, Valid: true, Encryptor: e}.Value()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if clearText, err := e.Decrypt(value.(string)); err != nil || clearText != `{"theme":"dark","beta":true}` {
+		t.Errorf("unexpected clear text: %q, %v", clearText, err)
+	}
+
+	j := EncryptedJSON[prefs]{Encryptor: e}
+	if err := j.Scan(value); err != nil || !j.Valid {
+		t.Errorf("unexpected result: %+v, %v", j, err)
+	}
+	if diff := cmp.Diff(want, j.V); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestDefaultEncryptor(t *testing.T) {
+	t.Cleanup(func() { SetDefaultEncryptor(nil) })
+
+	SetDefaultEncryptor(nil)
+	if _, err := (EncryptedString{String: "john@example.com", Valid: true}).Value(); !errors.Is(err, ConfigurationError) {
+		t.Errorf("expected ConfigurationError, got: %v", err)
+	}
+
+	SetDefaultEncryptor(New(testConfig()))
+	value, err := EncryptedString{String: "john@example.com", Valid: true}.Value()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var s EncryptedString
+	if err := s.Scan(value); err != nil || s.String != "john@example.com" {
+		t.Errorf("unexpected result: %+v, %v", s, err)
+	}
+}
@@ -0,0 +1,87 @@
+package signedid
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/keygenerator"
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+var secret = keygenerator.New([]byte("b3c631c314c0bbca50c1b2843150fe33"), 1000, sha256.New).GenerateKey([]byte(Salt), 64)
+
+func TestPurpose(t *testing.T) {
+	for input, want := range map[[2]string]string{
+		{"User", ""}:                "user",
+		{"User", "password_reset"}:  "user/password_reset",
+		{"Admin::Account", "login"}: "admin/account/login",
+		{"HTMLPage", ""}:            "html_page",
+		{"Oauth2Token", ""}:         "oauth2_token",
+	} {
+		if got := Purpose(input[0], input[1]); got != want {
+			t.Errorf("input: %q; want %q; got %q", input, want, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	v := New(secret)
+
+	signed, err := v.Generate("User", 42, codec.MetadataOption{Purpose: "password_reset"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var id int
+	if err := v.Verify(signed, "User", &id, codec.MetadataOption{Purpose: "password_reset"}); err != nil || id != 42 {
+		t.Errorf("unexpected result: %d, %v", id, err)
+	}
+
+	if err := v.Verify(signed, "User", &id, codec.MetadataOption{}); !errors.Is(err, codec.MismatchedPurposeError) {
+		t.Errorf("expected MismatchedPurposeError, got: %v", err)
+	}
+	if err := v.Verify(signed, "Account", &id, codec.MetadataOption{Purpose: "password_reset"}); !errors.Is(err, codec.MismatchedPurposeError) {
+		t.Errorf("expected MismatchedPurposeError, got: %v", err)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	signed, err := New(secret).Generate("User", 42, codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	v := verifier.New(codec.New(true, false).WithSerializer(codec.JSON), sha256.New, secret)
+	want, err := v.Generate(42, codec.MetadataOption{Purpose: "user"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if signed != string(want) {
+		t.Errorf("output mismatch: want %q; got %q", want, signed)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Now()
+	msgCodec := codec.New(true, false).WithSerializer(codec.JSON)
+
+	expiresIn := time.Minute
+	signed, err := NewWithCodec(msgCodec.WithClock(func() time.Time { return now }), secret).
+		Generate("User", "3f8b6c1e", codec.MetadataOption{ExpiresIn: &expiresIn})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var id string
+	later := NewWithCodec(msgCodec.WithClock(func() time.Time { return now.Add(2 * time.Minute) }), secret)
+	if err := later.Verify(signed, "User", &id, codec.MetadataOption{}); !errors.Is(err, codec.ExpiredError) {
+		t.Errorf("expected ExpiredError, got: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Package tokenfor mirrors ActiveRecord::TokenFor, the tokens of
+// generates_token_for, which embed a record id and a fingerprint of the
+// record, like generates_token_for :password_reset { password_salt.last(10) }.
+package tokenfor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+// VerifierName is the name of generated_token_verifier in
+// Rails.application.message_verifiers.
+const VerifierName = "active_record/token_for"
+
+var InvalidTokenError = errors.New("tokenfor: invalid token")
+
+// Fingerprint returns the value of the block of generates_token_for, which
+// must marshal to the same JSON as its as_json in Ruby.
+type Fingerprint func() (any, error)
+
+type Definition struct {
+	verifier  *verifier.Verifier
+	modelName string
+	purpose   string
+	expiresIn time.Duration
+}
+
+// New returns the definition of generates_token_for purpose in the model
+// named modelName, signed by the generated_token_verifier v. A zero
+// expiresIn never expires.
+func New(v *verifier.Verifier, modelName, purpose string, expiresIn time.Duration) *Definition {
+	if v == nil {
+		panic("tokenfor: empty verifier")
+	}
+
+	return &Definition{
+		verifier:  v,
+		modelName: modelName,
+		purpose:   purpose,
+		expiresIn: expiresIn,
+	}
+}
+
+// FullPurpose returns the purpose the tokens are signed with, which joins the
+// model name, the purpose and expires_in.to_s.
+func (d *Definition) FullPurpose() string {
+	expiresIn := ""
+	if d.expiresIn != 0 {
+		if d.expiresIn%time.Second == 0 {
+			expiresIn = strconv.FormatInt(int64(d.expiresIn/time.Second), 10)
+		} else {
+			expiresIn = strconv.FormatFloat(d.expiresIn.Seconds(), 'f', -1, 64)
+		}
+	}
+
+	return strings.Join([]string{d.modelName, d.purpose, expiresIn}, "\n")
+}
+
+func (d *Definition) metadata() codec.MetadataOption {
+	opt := codec.MetadataOption{Purpose: d.FullPurpose()}
+	if d.expiresIn != 0 {
+		opt.ExpiresIn = &d.expiresIn
+	}
+
+	return opt
+}
+
+// Generate returns the token of the record id, like
+// record.generate_token_for. fingerprint is nil for a definition without a
+// block.
+func (d *Definition) Generate(id any, fingerprint Fingerprint) (string, error) {
+	payload := []any{id}
+	if fingerprint != nil {
+		value, err := fingerprint()
+		if err != nil {
+			return "", err
+		}
+		payload = append(payload, value)
+	}
+
+	token, err := d.verifier.Generate(payload, d.metadata())
+	if err != nil {
+		return "", err
+	}
+
+	return string(token), nil
+}
+
+// Resolve decodes the record id of token into id, then checks that
+// fingerprint, which may load the record by id, still gives the embedded
+// value, like Model.find_by_token_for. A changed fingerprint gives
+// InvalidTokenError.
+func (d *Definition) Resolve(token string, id any, fingerprint Fingerprint) error {
+	var payload []any
+	if err := d.verifier.Verify([]byte(token), &payload, d.metadata()); err != nil {
+		return err
+	}
+
+	if len(payload) != 1 && len(payload) != 2 || (len(payload) == 2) != (fingerprint != nil) {
+		return InvalidTokenError
+	}
+
+	if err := assign(id, payload[0]); err != nil {
+		return err
+	}
+
+	if fingerprint == nil {
+		return nil
+	}
+
+	value, err := fingerprint()
+	if err != nil {
+		return err
+	}
+
+	got, err := normalize(payload[1])
+	if err != nil {
+		return err
+	}
+	want, err := normalize(value)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(got, want) {
+		return InvalidTokenError
+	}
+
+	return nil
+}
+
+func assign(dst, src any) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("%w: %w", InvalidTokenError, err)
+	}
+
+	return nil
+}
+
+// normalize gives the JSON value of v, so fingerprints compare like as_json.
+func normalize(v any) (any, error) {
+	var normalized any
+	if err := assign(&normalized, v); err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
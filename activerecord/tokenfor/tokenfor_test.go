@@ -0,0 +1,98 @@
+package tokenfor
+
+import (
+	"crypto/sha1"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+func testVerifier(now time.Time) *verifier.Verifier {
+	serializer, err := codec.SerializerWithFallback("json_allow_marshal")
+	if err != nil {
+		panic(err)
+	}
+
+	msgCodec := codec.New(false, false).WithSerializer(serializer).WithClock(func() time.Time { return now })
+
+	return verifier.New(msgCodec, sha1.New, []byte("secret"))
+}
+
+func TestFullPurpose(t *testing.T) {
+	v := testVerifier(time.Now())
+
+	for want, d := range map[string]*Definition{
+		"User\npassword_reset\n900":  New(v, "User", "password_reset", 15*time.Minute),
+		"User\nemail_confirmation\n": New(v, "User", "email_confirmation", 0),
+		"User\nshort\n1.5":           New(v, "User", "short", 1500*time.Millisecond),
+	} {
+		if got := d.FullPurpose(); got != want {
+			t.Errorf("want %q; got %q", want, got)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	d := New(testVerifier(time.Now()), "User", "password_reset", 15*time.Minute)
+	salt := "aBcDeFgHiJ"
+	fingerprint := func() (any, error) { return salt, nil }
+
+	token, err := d.Generate(42, fingerprint)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var id int64
+	if err := d.Resolve(token, &id, fingerprint); err != nil || id != 42 {
+		t.Errorf("unexpected result: %d, %v", id, err)
+	}
+
+	salt = "kLmNoPqRsT"
+	if err := d.Resolve(token, &id, fingerprint); !errors.Is(err, InvalidTokenError) {
+		t.Errorf("expected InvalidTokenError, got: %v", err)
+	}
+
+	other := New(testVerifier(time.Now()), "User", "email_confirmation", 15*time.Minute)
+	if err := other.Resolve(token, &id, fingerprint); !errors.Is(err, codec.MismatchedPurposeError) {
+		t.Errorf("expected MismatchedPurposeError, got: %v", err)
+	}
+}
+
+func TestWithoutFingerprint(t *testing.T) {
+	d := New(testVerifier(time.Now()), "User", "unsubscribe", 0)
+
+	token, err := d.Generate("3f8b6c1e", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var id string
+	if err := d.Resolve(token, &id, nil); err != nil || id != "3f8b6c1e" {
+		t.Errorf("unexpected result: %q, %v", id, err)
+	}
+
+	fingerprint := func() (any, error) { return nil, nil }
+	if err := d.Resolve(token, &id, fingerprint); !errors.Is(err, InvalidTokenError) {
+		t.Errorf("expected InvalidTokenError, got: %v", err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Now()
+	token, err := New(testVerifier(now), "User", "password_reset", 15*time.Minute).Generate(42, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var id int64
+	later := New(testVerifier(now.Add(time.Hour)), "User", "password_reset", 15*time.Minute)
+	if err := later.Resolve(token, &id, nil); !errors.Is(err, codec.ExpiredError) {
+		t.Errorf("expected ExpiredError, got: %v", err)
+	}
+}
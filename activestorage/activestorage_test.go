@@ -0,0 +1,112 @@
+package activestorage
+
+import (
+	"crypto/sha1"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+func testVerifier(now time.Time) *Verifier {
+	serializer, err := codec.SerializerWithFallback("json_allow_marshal")
+	if err != nil {
+		panic(err)
+	}
+
+	msgCodec := codec.New(false, false).WithSerializer(serializer).WithClock(func() time.Time { return now })
+
+	return New(verifier.New(msgCodec, sha1.New, []byte("secret")))
+}
+
+func TestSignedBlobID(t *testing.T) {
+	v := testVerifier(time.Now())
+
+	signed, err := v.GenerateSignedBlobID(42, codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var id int64
+	if err := v.VerifySignedBlobID(signed, &id, codec.MetadataOption{}); err != nil || id != 42 {
+		t.Errorf("unexpected result: %d, %v", id, err)
+	}
+	if err := v.VerifySignedBlobID(signed, &id, codec.MetadataOption{Purpose: "user/blob_id"}); !errors.Is(err, codec.MismatchedPurposeError) {
+		t.Errorf("expected MismatchedPurposeError, got: %v", err)
+	}
+	if _, err := v.VerifyBlobKey(signed); !errors.Is(err, codec.MismatchedPurposeError) {
+		t.Errorf("expected MismatchedPurposeError, got: %v", err)
+	}
+}
+
+func TestBlobKey(t *testing.T) {
+	now := time.Now()
+	v := testVerifier(now)
+	want := NewBlobKey("xb1ioxnxqy5cjpz9rh5mkv3ytd1u", "avatar.png", "image/png", "inline", "local")
+
+	expiresIn := 5 * time.Minute
+	encoded, err := v.GenerateBlobKey(want, codec.MetadataOption{ExpiresIn: &expiresIn})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if got, err := v.VerifyBlobKey(encoded); err != nil || got != want {
+		t.Errorf("unexpected result: %+v, %v", got, err)
+	}
+
+	if _, err := testVerifier(now.Add(time.Hour)).VerifyBlobKey(encoded); !errors.Is(err, codec.ExpiredError) {
+		t.Errorf("expected ExpiredError, got: %v", err)
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	for input, want := range map[[2]string]string{
+		{"inline", "avatar.png"}:        `inline; filename="avatar.png"; filename*=UTF-8''avatar.png`,
+		{"attachment", "my file.pdf"}:   `attachment; filename="my file.pdf"; filename*=UTF-8''my%20file.pdf`,
+		{"", "résumé.pdf"}:              `inline; filename="resume.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`,
+		{"attachment", "日本.txt"}:        `attachment; filename="%3F%3F.txt"; filename*=UTF-8''%E6%97%A5%E6%9C%AC.txt`,
+		{"attachment", " a/b:c&d.txt "}: `attachment; filename="a-b-c%26d.txt"; filename*=UTF-8''a-b-c&d.txt`,
+	} {
+		if got := ContentDisposition(input[0], input[1]); got != want {
+			t.Errorf("input: %q; want %q; got %q", input, want, got)
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	if got, want := BlobRedirectPath("eyJfcmFpbHMiOnt9fQ==--abc", "my photo.jpg"), "/rails/active_storage/blobs/redirect/eyJfcmFpbHMiOnt9fQ==--abc/my%20photo.jpg"; got != want {
+		t.Errorf("want %q; got %q", want, got)
+	}
+	if got, want := DiskServicePath("eyJ9--abc", "a?b.png"), "/rails/active_storage/disk/eyJ9--abc/a-b.png"; got != want {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
+
+func TestDiskPathFor(t *testing.T) {
+	if got, err := DiskPathFor("/storage", "xb1ioxnxqy5cjpz9rh5mkv3ytd1u"); err != nil || got != "/storage/xb/1i/xb1ioxnxqy5cjpz9rh5mkv3ytd1u" {
+		t.Errorf("unexpected result: %q, %v", got, err)
+	}
+	if got, err := DiskPathFor("/", "xb1ioxnxqy5cjpz9rh5mkv3ytd1u"); err != nil || got != "/xb/1i/xb1ioxnxqy5cjpz9rh5mkv3ytd1u" {
+		t.Errorf("unexpected result: %q, %v", got, err)
+	}
+
+	for key, want := range map[string]string{
+		"abc":   "/storage/ab/c/abc",
+		"a":     "/storage/a/a",
+		"日本語です": "/storage/日本/語で/日本語です",
+	} {
+		if got, err := DiskPathFor("/storage", key); err != nil || got != want {
+			t.Errorf("key: %q; unexpected result: %q, %v", key, got, err)
+		}
+	}
+
+	for _, key := range []string{"", "   ", "xb1i\x00", "../../../../etc/passwd", "xb1i/../../../../etc"} {
+		if _, err := DiskPathFor("/storage", key); !errors.Is(err, InvalidKeyError) {
+			t.Errorf("key: %q; expected InvalidKeyError, got: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package activestorage
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+var InvalidKeyError = errors.New("activestorage: invalid key")
+
+// SanitizeFilename mirrors ActiveStorage::Filename#sanitized.
+func SanitizeFilename(filename string) string {
+	filename = strings.ToValidUTF8(filename, "�")
+	filename = strings.TrimRight(strings.TrimLeft(filename, "\t\n\v\f\r "), "\x00\t\n\v\f\r ")
+
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("\u202e%$|:;/<>?*\"\t\r\n\\", r) {
+			return '-'
+		}
+		return r
+	}, filename)
+}
+
+// ContentDisposition mirrors content_disposition_with of the services, which
+// falls back to "inline" for an unknown disposition and sanitizes filename.
+func ContentDisposition(disposition, filename string) string {
+	if disposition != "attachment" {
+		disposition = "inline"
+	}
+
+	filename = SanitizeFilename(filename)
+
+	return fmt.Sprintf("%s; filename=\"%s\"; filename*=UTF-8''%s",
+		disposition,
+		percentEscape(transliterate(filename), isTraditionalChar),
+		percentEscape(filename, isRFC5987Char))
+}
+
+func isTraditionalChar(c byte) bool {
+	return c == ' ' || isRFC5987Char(c) && c != '&'
+}
+
+func isRFC5987Char(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		strings.IndexByte("!#$&+.^_`|~-", c) >= 0
+}
+
+func percentEscape(s string, keep func(byte) bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if keep(s[i]) {
+			b.WriteByte(s[i])
+		} else {
+			fmt.Fprintf(&b, "%%%02X", s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// approximations are the Latin-1 approximations of I18n.transliterate. Any
+// other non-ASCII character becomes "?".
+var approximations = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Æ': "AE", 'Ç': "C",
+	'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E", 'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I",
+	'Ð': "D", 'Ñ': "N", 'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", '×': "x",
+	'Ø': "O", 'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ý': "Y", 'Þ': "Th", 'ß': "ss",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae", 'ç': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ð': "d", 'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'þ': "th", 'ÿ': "y",
+}
+
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch approximation, ok := approximations[r]; {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case ok:
+			b.WriteString(approximation)
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
+
+// BlobRedirectPath returns the path of rails_service_blob_path, which
+// redirects to the service URL of the blob.
+func BlobRedirectPath(signedID, filename string) string {
+	return "/rails/active_storage/blobs/redirect/" + escapePath(signedID) + "/" + escapePath(SanitizeFilename(filename))
+}
+
+// DiskServicePath returns the path of rails_disk_service_path, which serves
+// the file of an encoded key.
+func DiskServicePath(encodedKey, filename string) string {
+	return "/rails/active_storage/disk/" + escapePath(encodedKey) + "/" + escapePath(SanitizeFilename(filename))
+}
+
+// DiskPathFor returns the path of the file of key under the root of a Disk
+// service, like DiskService#path_for. Like Rails, it returns InvalidKeyError
+// for a blank key, or one which resolves outside of root.
+func DiskPathFor(root, key string) (string, error) {
+	switch {
+	case strings.TrimSpace(key) == "":
+		return "", fmt.Errorf("%w: key is blank", InvalidKeyError)
+	case strings.ContainsRune(key, 0):
+		return "", fmt.Errorf("%w: key is invalid", InvalidKeyError)
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+
+	// folder_for slices characters, and shorter keys give shorter folders
+	chars := []rune(key)
+	n := len(chars)
+
+	path := filepath.Join(root, string(chars[:min(2, n)]), string(chars[min(2, n):min(4, n)]), key)
+	if !strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: key is outside of disk service root", InvalidKeyError)
+	}
+
+	return path, nil
+}
+
+// escapePath mirrors the escaping of path segments by the Rails router,
+// which keeps unreserved characters, sub-delims, ":" and "@".
+func escapePath(s string) string {
+	return percentEscape(s, func(c byte) bool {
+		return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			strings.IndexByte("-._~!$&'()*+,;=:@", c) >= 0
+	})
+}
@@ -0,0 +1,168 @@
+package activestorage
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/atitan/activesupport-go/marshal"
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/ruby"
+)
+
+const VariationPurpose = "variation"
+
+var InvalidVariationError = errors.New("activestorage: invalid variation")
+
+// Transformation is one transformation of a variant, like resize_to_limit:
+// [100, 100]. Decoded arguments keep their Ruby representation: integers are
+// int64, hashes *ruby.Hash, and symbols of legacy Marshal keys ruby.Symbol.
+type Transformation struct {
+	Name     string
+	Argument any
+}
+
+// Variation mirrors ActiveStorage::Variation, the ordered transformations of
+// a variant.
+type Variation struct {
+	Transformations []Transformation
+}
+
+// Get returns the argument of the transformation name.
+func (v Variation) Get(name string) (any, bool) {
+	for _, t := range v.Transformations {
+		if t.Name == name {
+			return t.Argument, true
+		}
+	}
+
+	return nil, false
+}
+
+// Format returns the format of the variant, which defaults to png.
+func (v Variation) Format() string {
+	format, ok := v.Get("format")
+	if !ok {
+		return "png"
+	}
+
+	return fmt.Sprint(format)
+}
+
+// Digest returns the digest of the variant records of the variation, like
+// Variation#digest, with the keys of nested hashes symbolized like
+// deep_symbolize_keys.
+func (v Variation) Digest() (string, error) {
+	dumped, err := marshal.Marshal(v.hash())
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum(dumped)
+
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+func (v Variation) hash() *ruby.Hash {
+	h := ruby.NewHash()
+	for _, t := range v.Transformations {
+		h.Set(ruby.Symbol(t.Name), symbolizeKeys(t.Argument))
+	}
+
+	return h
+}
+
+// symbolizeKeys mirrors deep_symbolize_keys. Maps are sorted by key, as
+// they have no order.
+func symbolizeKeys(v any) any {
+	switch v := v.(type) {
+	case *ruby.Hash:
+		if v == nil {
+			return v
+		}
+
+		h := ruby.NewHash()
+		for _, pair := range v.Pairs {
+			h.Set(symbolizeKey(pair.Key), symbolizeKeys(pair.Value))
+		}
+
+		return h
+	case ruby.Hash:
+		return symbolizeKeys(&v)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		h := ruby.NewHash()
+		for _, key := range keys {
+			h.Set(ruby.Symbol(key), symbolizeKeys(v[key]))
+		}
+
+		return h
+	case []any:
+		symbolized := make([]any, len(v))
+		for i := range v {
+			symbolized[i] = symbolizeKeys(v[i])
+		}
+
+		return symbolized
+	}
+
+	return v
+}
+
+func symbolizeKey(key any) any {
+	if s, ok := key.(string); ok {
+		return ruby.Symbol(s)
+	}
+
+	return key
+}
+
+// GenerateVariationKey returns the key of variation, like Variation#key.
+func (v *Verifier) GenerateVariationKey(variation Variation) (string, error) {
+	key, err := v.verifier.Generate(variation.hash(), codec.MetadataOption{Purpose: VariationPurpose})
+	if err != nil {
+		return "", err
+	}
+
+	return string(key), nil
+}
+
+// VerifyVariationKey decodes a variation key, like Variation.decode.
+func (v *Verifier) VerifyVariationKey(key string) (Variation, error) {
+	var h ruby.Hash
+	if err := v.verifier.Verify([]byte(key), &h, codec.MetadataOption{Purpose: VariationPurpose}); err != nil {
+		return Variation{}, err
+	}
+
+	variation := Variation{Transformations: make([]Transformation, 0, h.Len())}
+	for _, pair := range h.Pairs {
+		var name string
+		switch k := pair.Key.(type) {
+		case string:
+			name = k
+		case ruby.Symbol:
+			name = string(k)
+		default:
+			return Variation{}, fmt.Errorf("%w: transformation %v", InvalidVariationError, pair.Key)
+		}
+
+		variation.Transformations = append(variation.Transformations, Transformation{Name: name, Argument: pair.Value})
+	}
+
+	return variation, nil
+}
+
+// RepresentationRedirectPath returns the path of
+// rails_blob_representation_path, which redirects to the service URL of the
+// variant.
+func RepresentationRedirectPath(signedBlobID, variationKey, filename string) string {
+	return "/rails/active_storage/representations/redirect/" + escapePath(signedBlobID) + "/" +
+		escapePath(variationKey) + "/" + escapePath(SanitizeFilename(filename))
+}
@@ -0,0 +1,150 @@
+package activestorage
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+	"github.com/atitan/activesupport-go/ruby"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVariationKey(t *testing.T) {
+	v := testVerifier(time.Now())
+	variation := Variation{Transformations: []Transformation{
+		{Name: "resize_to_limit", Argument: []any{int64(100), int64(100)}},
+		{Name: "format", Argument: "webp"},
+		{Name: "saver", Argument: ruby.NewHash(ruby.Pair{Key: "quality", Value: int64(80)})},
+	}}
+
+	key, err := v.GenerateVariationKey(variation)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var data string
+	serializer, _ := codec.SerializerWithFallback("json_allow_marshal")
+	raw := verifier.New(codec.New(false, false).WithSerializer(serializer), sha1.New, []byte("secret"))
+	if err := raw.Verify([]byte(key), &data, codec.MetadataOption{Purpose: VariationPurpose}); err == nil {
+		t.Error("expected a hash")
+	}
+
+	got, err := v.VerifyVariationKey(key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(variation, got); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+	if got.Format() != "webp" {
+		t.Errorf("unexpected format: %q", got.Format())
+	}
+
+	if _, err := v.VerifyVariationKey(BlobRedirectPath("a", "b")); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := v.VerifyBlobKey(key); !errors.Is(err, codec.MismatchedPurposeError) {
+		t.Errorf("expected MismatchedPurposeError, got: %v", err)
+	}
+}
+
+func TestLegacyVariationKey(t *testing.T) {
+	v := New(verifier.New(codec.New(false, true).WithSerializer(codec.Marshal), sha1.New, []byte("secret")))
+	variation := Variation{Transformations: []Transformation{
+		{Name: "resize_to_fill", Argument: []any{int64(64), int64(64)}},
+		{Name: "format", Argument: ruby.Symbol("jpg")},
+	}}
+
+	key, err := v.GenerateVariationKey(variation)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	got, err := v.VerifyVariationKey(key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(variation, got); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+	if got.Format() != "jpg" {
+		t.Errorf("unexpected format: %q", got.Format())
+	}
+}
+
+func TestVariationDigest(t *testing.T) {
+	// Marshal.dump({resize_to_limit: [100, 100]})
+	variation := Variation{Transformations: []Transformation{{Name: "resize_to_limit", Argument: []any{100, 100}}}}
+
+	got, err := variation.Digest()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	sum := sha1.Sum([]byte("\x04\x08{\x06:\x14resize_to_limit[\x07iiii"))
+	if want := base64.StdEncoding.EncodeToString(sum[:]); got != want {
+		t.Errorf("want %q; got %q", want, got)
+	}
+
+	if (Variation{}).Format() != "png" {
+		t.Error("unexpected default format")
+	}
+}
+
+func TestVariationDigestRails(t *testing.T) {
+	want, err := os.ReadFile("testdata/TestVariationDigestRails.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// A variation decoded from a JSON key has String keys in nested hashes
+	var h ruby.Hash
+	if err := json.Unmarshal([]byte(`{"resize_to_limit":[100,100],"saver":{"quality":80}}`), &h); err != nil {
+		t.Error(err)
+		return
+	}
+
+	v := testVerifier(time.Now())
+	key, err := v.GenerateVariationKey(Variation{Transformations: []Transformation{
+		{Name: "resize_to_limit", Argument: h.Pairs[0].Value},
+		{Name: "saver", Argument: h.Pairs[1].Value},
+	}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	variation, err := v.VerifyVariationKey(key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	got, err := variation.Digest()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if got != string(want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
+
+func TestRepresentationRedirectPath(t *testing.T) {
+	got := RepresentationRedirectPath("eyJ9--abc", "eyJ9--def", "avatar.webp")
+	if want := "/rails/active_storage/representations/redirect/eyJ9--abc/eyJ9--def/avatar.webp"; got != want {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/atitan/activesupport-go/encryptedconfiguration"
+	"github.com/atitan/activesupport-go/encryptedfile"
+	"github.com/atitan/activesupport-go/rails"
+)
+
+// The template of rails credentials:edit, with a fresh secret_key_base.
+const credentialsTemplate = `# aws:
+#   access_key_id: 123
+#   secret_access_key: 345
+
+# Used as the base secret for all MessageVerifiers in Rails, including the one protecting cookies.
+secret_key_base: %s
+`
+
+var noEditorError = errors.New("no $VISUAL or $EDITOR to open file in")
+
+func runCredentials(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("credentials "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	root := flags.String("root", ".", "root of the Rails application")
+	env := flags.String("environment", "", "edit the credentials of an environment")
+	flags.StringVar(env, "e", "", "shorthand for -environment")
+	enroll := flags.Bool("enroll", false, "diff: enroll the project in credentials file diffing")
+	disenroll := flags.Bool("disenroll", false, "diff: disenroll the project from credentials file diffing")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	contentPath, keyPath := credentialsPaths(*env)
+	credentials := encryptedconfiguration.New(filepath.Join(*root, contentPath), filepath.Join(*root, keyPath), rails.MasterKeyEnv, false)
+
+	var err error
+	switch args[0] {
+	case "edit":
+		err = editCredentials(credentials, *root, keyPath, stdout)
+	case "show":
+		err = showCredentials(credentials, keyPath, stdout)
+	case "diff":
+		err = diffCredentials(*root, flags.Args(), *enroll, *disenroll, stdout)
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// credentialsPaths returns the content and key paths relative to the root,
+// like rails credentials with or without --environment.
+func credentialsPaths(env string) (string, string) {
+	if env == "" {
+		return filepath.Join("config", "credentials.yml.enc"), filepath.Join("config", "master.key")
+	}
+
+	return filepath.Join("config", "credentials", env+".yml.enc"), filepath.Join("config", "credentials", env+".key")
+}
+
+func showCredentials(credentials *encryptedconfiguration.Configuration, keyPath string, stdout io.Writer) error {
+	key, err := credentials.Key()
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("missing '%s' to decrypt credentials, see `activesupport-go credentials edit`", keyPath)
+	}
+
+	contents, err := credentials.Read()
+	if err != nil {
+		return err
+	}
+
+	_, err = stdout.Write(contents)
+	return err
+}
+
+func editCredentials(credentials *encryptedconfiguration.Configuration, root, keyPath string, stdout io.Writer) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if strings.TrimSpace(editor) == "" {
+		return noEditorError
+	}
+
+	key, err := credentials.Key()
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		if err := addKey(credentials, root, keyPath, stdout); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(credentials.ContentPath); errors.Is(err, os.ErrNotExist) {
+		if err := credentials.Write([]byte(fmt.Sprintf(credentialsTemplate, rails.GenerateSecretKeyBase()))); err != nil {
+			return err
+		}
+	}
+
+	err = credentials.Change(func(contents []byte) ([]byte, error) {
+		return editInEditor(editor, filepath.Base(strings.TrimSuffix(credentials.ContentPath, ".enc")), contents)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "File encrypted and saved.")
+	return ensureDiffDriver(root)
+}
+
+// addKey writes a fresh key file, and keeps it out of git.
+func addKey(credentials *encryptedconfiguration.Configuration, root, keyPath string, stdout io.Writer) error {
+	key := encryptedfile.GenerateKey()
+
+	if err := os.MkdirAll(filepath.Dir(credentials.KeyPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(credentials.KeyPath, []byte(key), 0600); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Adding %s to store the encryption key: %s\n\n", keyPath, key)
+	fmt.Fprintln(stdout, "Save this in a password manager your team can access.")
+	fmt.Fprintln(stdout, "If you lose the key, no one, including you, can access anything encrypted with it.")
+	fmt.Fprintln(stdout)
+
+	return ignoreKeyFile(filepath.Join(root, ".gitignore"), "/"+filepath.ToSlash(keyPath))
+}
+
+func ignoreKeyFile(gitignorePath, entry string) error {
+	contents, err := os.ReadFile(gitignorePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		if strings.TrimSpace(line) == entry {
+			return nil
+		}
+	}
+
+	var b bytes.Buffer
+	b.Write(contents)
+	if len(contents) > 0 && !bytes.HasSuffix(contents, []byte("\n")) {
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "\n# Ignore master key for decrypting credentials and more.\n%s\n", entry)
+
+	return os.WriteFile(gitignorePath, b.Bytes(), 0644)
+}
+
+// editInEditor opens contents in a private temporary file named after name.
+func editInEditor(editor, name string, contents []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "activesupport-go-credentials-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, contents, 0600); err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor: %w", err)
+	}
+
+	return os.ReadFile(path)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atitan/activesupport-go/rails"
+)
+
+// fakeEditor appends line to the edited file.
+func fakeEditor(t *testing.T, line string) string {
+	path := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\nprintf '%s\\n' '" + line + "' >> \"$1\"\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestCredentialsEdit(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(rails.MasterKeyEnv, "")
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", fakeEditor(t, "api_key: abc"))
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"credentials", "edit", "-root", root}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d: %s", code, stderr.String())
+		return
+	}
+	if !strings.Contains(stdout.String(), "Adding config/master.key") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+
+	gitignore, err := os.ReadFile(filepath.Join(root, ".gitignore"))
+	if err != nil || !strings.Contains(string(gitignore), "\n/config/master.key\n") {
+		t.Errorf("key file not ignored: %q, %v", gitignore, err)
+	}
+
+	credentials := rails.Credentials(root, "development", true)
+	if value, err := credentials.FetchString("api_key"); err != nil || value != "abc" {
+		t.Errorf("unexpected value: %v, %v", value, err)
+	}
+	if value, err := credentials.FetchString("secret_key_base"); err != nil || len(value) != 128 {
+		t.Errorf("unexpected secret_key_base: %v, %v", value, err)
+	}
+
+	// A second edit keeps the key, and does not repeat the ignore entry
+	t.Setenv("EDITOR", fakeEditor(t, "other: 1"))
+	stdout.Reset()
+	if code := run([]string{"credentials", "edit", "-root", root}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d: %s", code, stderr.String())
+		return
+	}
+	if strings.Contains(stdout.String(), "Adding") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+	if after, _ := os.ReadFile(filepath.Join(root, ".gitignore")); !bytes.Equal(after, gitignore) {
+		t.Errorf("gitignore changed: %q", after)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"credentials", "show", "-root", root}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d: %s", code, stderr.String())
+		return
+	}
+	if !strings.HasSuffix(stdout.String(), "api_key: abc\nother: 1\n") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestCredentialsEditEnvironment(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(rails.MasterKeyEnv, "")
+	t.Setenv("VISUAL", fakeEditor(t, "env: production"))
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"credentials", "edit", "-root", root, "-e", "production"}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d: %s", code, stderr.String())
+		return
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "config", "credentials", "production.key")); err != nil {
+		t.Error(err)
+	}
+	if value, err := rails.Credentials(root, "production", true).FetchString("env"); err != nil || value != "production" {
+		t.Errorf("unexpected value: %v, %v", value, err)
+	}
+}
+
+func TestCredentialsEditInvalidYAML(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(rails.MasterKeyEnv, "")
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", fakeEditor(t, "  bad: indentation"))
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"credentials", "edit", "-root", root}, &stdout, &stderr); code != 1 {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	if !strings.Contains(stderr.String(), "invalid YAML") {
+		t.Errorf("unexpected error: %s", stderr.String())
+	}
+
+	// The previous content is kept
+	if _, err := rails.Credentials(root, "development", true).FetchString("secret_key_base"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCredentialsShowMissingKey(t *testing.T) {
+	t.Setenv(rails.MasterKeyEnv, "")
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"credentials", "show", "-root", t.TempDir()}, &stdout, &stderr); code != 1 {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	if !strings.Contains(stderr.String(), "missing 'config/master.key'") {
+		t.Errorf("unexpected error: %s", stderr.String())
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/atitan/activesupport-go/encryptedconfiguration"
+	"github.com/atitan/activesupport-go/rails"
+)
+
+// The same entries as rails credentials:diff --enroll, so either tool can be
+// the diff driver.
+const (
+	gitattributesEntry = "config/credentials/*.yml.enc diff=rails_credentials\nconfig/credentials.yml.enc diff=rails_credentials\n"
+	diffDriverKey      = "diff.rails_credentials.textconv"
+	diffDriverCommand  = "activesupport-go credentials diff"
+)
+
+func diffCredentials(root string, args []string, enroll, disenroll bool, stdout io.Writer) error {
+	switch {
+	case enroll:
+		return enrollDiffing(root, stdout)
+	case disenroll:
+		return disenrollDiffing(root, stdout)
+	case len(args) != 1:
+		return fmt.Errorf("usage: %s CONTENT_PATH", diffDriverCommand)
+	}
+
+	return textconvCredentials(root, args[0], stdout)
+}
+
+// textconvCredentials prints the decrypted content of path, or its ciphertext
+// when it cannot be decrypted. Git passes a temporary copy for old versions,
+// so the environment is guessed from the suffix of path.
+func textconvCredentials(root, path string, stdout io.Writer) error {
+	keyPath := filepath.Join(root, "config", "master.key")
+	if env := environmentOfPath(root, path); env != "" {
+		keyPath = filepath.Join(root, "config", "credentials", env+".key")
+	}
+
+	contents, err := encryptedconfiguration.New(path, keyPath, rails.MasterKeyEnv, false).Read()
+	if err != nil || len(contents) == 0 {
+		if contents, err = os.ReadFile(path); err != nil {
+			return err
+		}
+	}
+
+	_, err = stdout.Write(contents)
+	return err
+}
+
+func environmentOfPath(root, path string) string {
+	matches, _ := filepath.Glob(filepath.Join(root, "config", "credentials", "*.yml.enc"))
+
+	for _, match := range matches {
+		env := strings.TrimSuffix(filepath.Base(match), ".yml.enc")
+		if strings.HasSuffix(path, env+".yml.enc") {
+			return env
+		}
+	}
+
+	return ""
+}
+
+func enrolledInDiffing(root string) bool {
+	contents, err := os.ReadFile(filepath.Join(root, ".gitattributes"))
+	return err == nil && bytes.Contains(contents, []byte(gitattributesEntry))
+}
+
+func enrollDiffing(root string, stdout io.Writer) error {
+	if enrolledInDiffing(root) {
+		fmt.Fprintln(stdout, "Project is already enrolled in credentials file diffing.")
+		return nil
+	}
+
+	f, err := os.OpenFile(filepath.Join(root, ".gitattributes"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(gitattributesEntry); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := ensureDiffDriver(root); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, "Enrolled project in credentials file diffing!")
+	return nil
+}
+
+func disenrollDiffing(root string, stdout io.Writer) error {
+	if !enrolledInDiffing(root) {
+		fmt.Fprintln(stdout, "Project is not enrolled in credentials file diffing.")
+		return nil
+	}
+
+	path := filepath.Join(root, ".gitattributes")
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Like Rails, an empty .gitattributes is removed
+	if contents = bytes.ReplaceAll(contents, []byte(gitattributesEntry), nil); len(contents) == 0 {
+		err = os.Remove(path)
+	} else {
+		err = os.WriteFile(path, contents, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if gitConfig(root, "--get", diffDriverKey) == nil {
+		if err := gitConfig(root, "--unset", diffDriverKey); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(stdout, "Disenrolled project from credentials file diffing!")
+	return nil
+}
+
+// ensureDiffDriver configures the textconv command in the git config of the
+// project when it is enrolled, like rails credentials:edit does.
+func ensureDiffDriver(root string) error {
+	if !enrolledInDiffing(root) || gitConfig(root, "--get", diffDriverKey) == nil {
+		return nil
+	}
+
+	return gitConfig(root, diffDriverKey, diffDriverCommand)
+}
+
+func gitConfig(root string, args ...string) error {
+	cmd := exec.Command("git", append([]string{"-C", root, "config"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git config: %w: %s", err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atitan/activesupport-go/rails"
+)
+
+func TestCredentialsDiffTextconv(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(rails.MasterKeyEnv, "")
+	t.Setenv("VISUAL", fakeEditor(t, "env: production"))
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"credentials", "edit", "-root", root, "-e", "production"}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d: %s", code, stderr.String())
+		return
+	}
+
+	// Git hands old versions over as temporary copies
+	encrypted, err := os.ReadFile(filepath.Join(root, "config", "credentials", "production.yml.enc"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmp := filepath.Join(t.TempDir(), "XyZ123_production.yml.enc")
+	if err := os.WriteFile(tmp, encrypted, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	stdout.Reset()
+	if code := run([]string{"credentials", "diff", "-root", root, tmp}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d: %s", code, stderr.String())
+		return
+	}
+	if !strings.HasSuffix(stdout.String(), "env: production\n") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+
+	// Without the key, the ciphertext is shown
+	if err := os.Remove(filepath.Join(root, "config", "credentials", "production.key")); err != nil {
+		t.Error(err)
+		return
+	}
+	stdout.Reset()
+	if code := run([]string{"credentials", "diff", "-root", root, tmp}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d: %s", code, stderr.String())
+		return
+	}
+	if stdout.String() != string(encrypted) {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestCredentialsDiffEnroll(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	root := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", root).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".gitattributes"), []byte("*.png binary\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	for i := 0; i < 2; i++ {
+		if code := run([]string{"credentials", "diff", "-root", root, "-enroll"}, &stdout, &stderr); code != 0 {
+			t.Errorf("exit code %d: %s", code, stderr.String())
+			return
+		}
+	}
+	if !strings.Contains(stdout.String(), "already enrolled") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+
+	gitattributes, _ := os.ReadFile(filepath.Join(root, ".gitattributes"))
+	if string(gitattributes) != "*.png binary\n"+gitattributesEntry {
+		t.Errorf("unexpected .gitattributes: %q", gitattributes)
+	}
+
+	out, err := exec.Command("git", "-C", root, "config", "--get", diffDriverKey).Output()
+	if err != nil || strings.TrimSpace(string(out)) != diffDriverCommand {
+		t.Errorf("diff driver not configured: %q, %v", out, err)
+	}
+
+	if code := run([]string{"credentials", "diff", "-root", root, "--disenroll"}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d: %s", code, stderr.String())
+		return
+	}
+
+	gitattributes, _ = os.ReadFile(filepath.Join(root, ".gitattributes"))
+	if string(gitattributes) != "*.png binary\n" {
+		t.Errorf("unexpected .gitattributes: %q", gitattributes)
+	}
+	if err := exec.Command("git", "-C", root, "config", "--get", diffDriverKey).Run(); err == nil {
+		t.Error("diff driver still configured")
+	}
+
+	stdout.Reset()
+	if code := run([]string{"credentials", "diff", "-root", root, "--disenroll"}, &stdout, &stderr); code != 0 {
+		t.Errorf("exit code %d: %s", code, stderr.String())
+		return
+	}
+	if !strings.Contains(stdout.String(), "not enrolled") {
+		t.Errorf("unexpected output: %s", stdout.String())
+	}
+}
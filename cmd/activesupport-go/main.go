@@ -0,0 +1,40 @@
+// Command activesupport-go manages Rails credentials without a Ruby
+// toolchain.
+//
+// Usage:
+//
+//	activesupport-go credentials edit [-root dir] [-environment env]
+//	activesupport-go credentials show [-root dir] [-environment env]
+//	activesupport-go credentials diff [-root dir] [-enroll | -disenroll | CONTENT_PATH]
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage:
+  activesupport-go credentials edit [-root dir] [-environment env]
+  activesupport-go credentials show [-root dir] [-environment env]
+  activesupport-go credentials diff [-root dir] [-enroll | -disenroll | CONTENT_PATH]
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "credentials":
+		return runCredentials(args[1:], stdout, stderr)
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+}
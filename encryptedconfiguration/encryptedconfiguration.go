@@ -0,0 +1,191 @@
+// Package encryptedconfiguration mirrors ActiveSupport::EncryptedConfiguration,
+// the YAML configuration behind Rails.application.credentials.
+package encryptedconfiguration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/atitan/activesupport-go/encryptedfile"
+	"github.com/atitan/activesupport-go/internal/yaml"
+)
+
+var (
+	InvalidContentError = errors.New("encryptedconfiguration: invalid YAML content")
+	KeyNotFoundError    = errors.New("encryptedconfiguration: key not found")
+	UnexpectedTypeError = errors.New("encryptedconfiguration: unexpected type")
+)
+
+type Configuration struct {
+	*encryptedfile.File
+
+	mu     sync.Mutex
+	config map[string]any
+}
+
+func New(contentPath, keyPath, envKey string, raiseIfMissingKey bool) *Configuration {
+	return &Configuration{
+		File: encryptedfile.New(contentPath, keyPath, envKey, raiseIfMissingKey),
+	}
+}
+
+// Read decrypts the YAML content. A missing content file, or a missing key
+// without RaiseIfMissingKey, reads as empty, like Rails.
+func (c *Configuration) Read() ([]byte, error) {
+	contents, err := c.File.Read()
+	if errors.Is(err, encryptedfile.MissingContentError) {
+		return nil, nil
+	}
+
+	return contents, err
+}
+
+// Write validates contents as YAML before encrypting them.
+func (c *Configuration) Write(contents []byte) error {
+	if _, err := parse(contents); err != nil {
+		return err
+	}
+
+	if err := c.File.Write(contents); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.config = nil
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Change passes the decrypted YAML to fn and writes back its result, unless
+// it is unchanged.
+func (c *Configuration) Change(fn func(contents []byte) ([]byte, error)) error {
+	contents, err := c.File.Read()
+	missing := errors.Is(err, encryptedfile.MissingContentError)
+	if err != nil && !missing {
+		return err
+	}
+
+	changed, err := fn(contents)
+	if err != nil {
+		return err
+	}
+
+	if !missing && bytes.Equal(changed, contents) {
+		return nil
+	}
+
+	return c.Write(changed)
+}
+
+// Config returns the parsed configuration, decrypting it on first use.
+func (c *Configuration) Config() (map[string]any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config != nil {
+		return c.config, nil
+	}
+
+	contents, err := c.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := parse(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	c.config = config
+	return config, nil
+}
+
+func parse(contents []byte) (map[string]any, error) {
+	parsed, err := yaml.Unmarshal(contents)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", InvalidContentError, err)
+	}
+
+	switch parsed := parsed.(type) {
+	case nil:
+		return map[string]any{}, nil
+	case map[string]any:
+		return parsed, nil
+	default:
+		return nil, fmt.Errorf("%w: not a mapping", InvalidContentError)
+	}
+}
+
+// Dig returns the value at the path of keys, or nil when any is missing,
+// like credentials.dig.
+func (c *Configuration) Dig(keys ...string) (any, error) {
+	value, err := c.Fetch(keys...)
+	if errors.Is(err, KeyNotFoundError) {
+		return nil, nil
+	}
+
+	return value, err
+}
+
+// Fetch returns the value at the path of keys, or KeyNotFoundError when any
+// is missing, like credentials.fetch.
+func (c *Configuration) Fetch(keys ...string) (any, error) {
+	config, err := c.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	var value any = config
+	for i, key := range keys {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", KeyNotFoundError, strings.Join(keys[:i+1], "."))
+		}
+
+		if value, ok = m[key]; !ok {
+			return nil, fmt.Errorf("%w: %s", KeyNotFoundError, strings.Join(keys[:i+1], "."))
+		}
+	}
+
+	return value, nil
+}
+
+// FetchString fetches a string, like credentials.fetch but typed.
+func (c *Configuration) FetchString(keys ...string) (string, error) {
+	return fetchAs[string](c, keys)
+}
+
+// FetchInt fetches an integer.
+func (c *Configuration) FetchInt(keys ...string) (int64, error) {
+	return fetchAs[int64](c, keys)
+}
+
+// FetchBool fetches a boolean.
+func (c *Configuration) FetchBool(keys ...string) (bool, error) {
+	return fetchAs[bool](c, keys)
+}
+
+// FetchMap fetches a nested configuration.
+func (c *Configuration) FetchMap(keys ...string) (map[string]any, error) {
+	return fetchAs[map[string]any](c, keys)
+}
+
+func fetchAs[T any](c *Configuration, keys []string) (T, error) {
+	var zero T
+
+	value, err := c.Fetch(keys...)
+	if err != nil {
+		return zero, err
+	}
+
+	typed, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s is %T", UnexpectedTypeError, strings.Join(keys, "."), value)
+	}
+
+	return typed, nil
+}
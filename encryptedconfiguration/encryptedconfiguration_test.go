@@ -0,0 +1,122 @@
+package encryptedconfiguration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newConfiguration(t *testing.T) *Configuration {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "master.key"), []byte("4aa19bef10a27fd29e09058b10e8c279"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(filepath.Join(dir, "credentials.yml.enc"), filepath.Join(dir, "master.key"), "TEST_MASTER_KEY", false)
+}
+
+func TestLookup(t *testing.T) {
+	c := newConfiguration(t)
+	if err := c.Write([]byte("aws:\n  access_key_id: AKIA\n  port: 443\n  enabled: true\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if value, err := c.Dig("aws", "access_key_id"); err != nil || value != "AKIA" {
+		t.Errorf("unexpected value: %v, %v", value, err)
+	}
+	if value, err := c.Dig("aws", "missing", "deeper"); err != nil || value != nil {
+		t.Errorf("unexpected value: %v, %v", value, err)
+	}
+
+	if value, err := c.FetchString("aws", "access_key_id"); err != nil || value != "AKIA" {
+		t.Errorf("unexpected value: %v, %v", value, err)
+	}
+	if value, err := c.FetchInt("aws", "port"); err != nil || value != 443 {
+		t.Errorf("unexpected value: %v, %v", value, err)
+	}
+	if value, err := c.FetchBool("aws", "enabled"); err != nil || !value {
+		t.Errorf("unexpected value: %v, %v", value, err)
+	}
+	if value, err := c.FetchMap("aws"); err != nil || len(value) != 3 {
+		t.Errorf("unexpected value: %v, %v", value, err)
+	}
+
+	if _, err := c.Fetch("aws", "secret_access_key"); !errors.Is(err, KeyNotFoundError) {
+		t.Errorf("expected KeyNotFoundError, got: %v", err)
+	}
+	if _, err := c.FetchString("aws", "port"); !errors.Is(err, UnexpectedTypeError) {
+		t.Errorf("expected UnexpectedTypeError, got: %v", err)
+	}
+}
+
+func TestChangeUnchanged(t *testing.T) {
+	f := newConfiguration(t)
+	if err := f.Write([]byte("a: 1\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	before, err := os.ReadFile(f.ContentPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = f.Change(func(contents []byte) ([]byte, error) {
+		return contents, nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	after, err := os.ReadFile(f.ContentPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(after) != string(before) {
+		t.Errorf("file rewritten: %s, %s", before, after)
+	}
+}
+
+func TestMissingContent(t *testing.T) {
+	config, err := newConfiguration(t).Config()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(config) != 0 {
+		t.Errorf("unexpected config: %v", config)
+	}
+}
+
+func TestMissingKey(t *testing.T) {
+	c := newConfiguration(t)
+	if err := c.Write([]byte("secret: 1\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	c = New(c.ContentPath, filepath.Join(filepath.Dir(c.KeyPath), "missing.key"), "TEST_MASTER_KEY", false)
+	if contents, err := c.Read(); err != nil || len(contents) != 0 {
+		t.Errorf("unexpected contents: %q, %v", contents, err)
+	}
+
+	config, err := c.Config()
+	if err != nil || len(config) != 0 {
+		t.Errorf("unexpected config: %v, %v", config, err)
+	}
+}
+
+func TestInvalidContent(t *testing.T) {
+	c := newConfiguration(t)
+
+	for _, contents := range []string{"a: 1\na: 2\n", "- a\n"} {
+		if err := c.Write([]byte(contents)); !errors.Is(err, InvalidContentError) {
+			t.Errorf("input: %q; expected InvalidContentError, got: %v", contents, err)
+		}
+	}
+}
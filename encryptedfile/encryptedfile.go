@@ -0,0 +1,193 @@
+// Package encryptedfile mirrors ActiveSupport::EncryptedFile, which stores
+// files like config/credentials.yml.enc encrypted with aes-128-gcm under a
+// hex key read from an environment variable or a key file.
+package encryptedfile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/encryptor"
+)
+
+const (
+	Cipher = "aes-128-gcm"
+	// KeyLen is the length of the hex key.
+	KeyLen = 32
+)
+
+var (
+	MissingContentError   = errors.New("encryptedfile: missing encrypted content file")
+	MissingKeyError       = errors.New("encryptedfile: missing encryption key")
+	InvalidKeyLengthError = errors.New("encryptedfile: encryption key must be 32 hex characters")
+	InvalidKeyError       = errors.New("encryptedfile: encryption key must be hex")
+)
+
+type File struct {
+	ContentPath       string
+	KeyPath           string
+	EnvKey            string
+	RaiseIfMissingKey bool
+}
+
+func New(contentPath, keyPath, envKey string, raiseIfMissingKey bool) *File {
+	return &File{
+		ContentPath:       contentPath,
+		KeyPath:           keyPath,
+		EnvKey:            envKey,
+		RaiseIfMissingKey: raiseIfMissingKey,
+	}
+}
+
+// GenerateKey returns a new hex key, like EncryptedFile.generate_key.
+func GenerateKey() string {
+	b := make([]byte, KeyLen/2)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Key returns the hex key from the environment variable, then from the key
+// file. Without one, it returns an empty key, or MissingKeyError when
+// RaiseIfMissingKey is set. Like Rails, only the key file is stripped, and a
+// blank environment variable is ignored.
+func (f *File) Key() (string, error) {
+	key := ""
+	if env := os.Getenv(f.EnvKey); f.EnvKey != "" && strings.TrimSpace(env) != "" {
+		key = env
+	}
+
+	if key == "" && f.KeyPath != "" {
+		contents, err := os.ReadFile(f.KeyPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		key = strings.TrimSpace(string(contents))
+	}
+
+	if key == "" {
+		if f.RaiseIfMissingKey {
+			return "", MissingKeyError
+		}
+		return "", nil
+	}
+
+	if len(key) != KeyLen {
+		return "", InvalidKeyLengthError
+	}
+	if _, err := hex.DecodeString(key); err != nil {
+		return "", InvalidKeyError
+	}
+
+	return key, nil
+}
+
+// Read decrypts the content file. Like Rails, a missing key gives
+// MissingContentError unless RaiseIfMissingKey is set, and surrounding
+// whitespace of the file is ignored.
+func (f *File) Read() ([]byte, error) {
+	key, err := f.Key()
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, MissingContentError
+	}
+
+	encrypted, err := os.ReadFile(f.ContentPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, MissingContentError
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := f.encryptor()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted = bytes.TrimSpace(encrypted)
+
+	var contents string
+	if err := e.Decrypt(encrypted, &contents, codec.MetadataOption{}); err != nil {
+		return nil, err
+	}
+
+	return []byte(contents), nil
+}
+
+// Write encrypts contents into the content file, replacing it atomically.
+func (f *File) Write(contents []byte) error {
+	e, err := f.encryptor()
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := e.Encrypt(string(contents), codec.MetadataOption{})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.ContentPath), 0755); err != nil {
+		return err
+	}
+
+	tmp := f.ContentPath + ".tmp"
+	if err := os.WriteFile(tmp, encrypted, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, f.ContentPath)
+}
+
+// Change passes the decrypted contents to fn and writes back its result. A
+// missing content file gives empty contents. Like Rails, unchanged contents
+// are not encrypted again.
+func (f *File) Change(fn func(contents []byte) ([]byte, error)) error {
+	contents, err := f.Read()
+	missing := errors.Is(err, MissingContentError)
+	if err != nil && !missing {
+		return err
+	}
+
+	changed, err := fn(contents)
+	if err != nil {
+		return err
+	}
+
+	if !missing && bytes.Equal(changed, contents) {
+		return nil
+	}
+
+	return f.Write(changed)
+}
+
+// The contents are dumped as a Ruby String with Marshal, so that any Rails
+// version can load them.
+func (f *File) encryptor() (*encryptor.Encryptor, error) {
+	key, err := f.Key()
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, MissingKeyError
+	}
+
+	secret, _ := hex.DecodeString(key)
+
+	serializer, err := codec.SerializerWithFallback("marshal")
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptor.New(codec.New(false, false).WithSerializer(serializer), true, secret, nil, nil), nil
+}
@@ -0,0 +1,193 @@
+package encryptedfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const key = "4aa19bef10a27fd29e09058b10e8c279"
+
+func newFile(t *testing.T) *File {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "master.key"), []byte(key+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(filepath.Join(dir, "credentials.yml.enc"), filepath.Join(dir, "master.key"), "TEST_MASTER_KEY", false)
+}
+
+func TestRoundTrip(t *testing.T) {
+	f := newFile(t)
+
+	if err := f.Write([]byte("secret: 1\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	contents, err := f.Read()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(contents) != "secret: 1\n" {
+		t.Errorf("data mismatch: %q", contents)
+	}
+
+	if _, err := os.Stat(f.ContentPath + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestChange(t *testing.T) {
+	f := newFile(t)
+
+	err := f.Change(func(contents []byte) ([]byte, error) {
+		return append(contents, "a: 1\n"...), nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	contents, err := f.Read()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(contents) != "a: 1\n" {
+		t.Errorf("data mismatch: %q", contents)
+	}
+}
+
+func TestChangeUnchanged(t *testing.T) {
+	f := newFile(t)
+	if err := f.Write([]byte("a: 1\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	before, err := os.ReadFile(f.ContentPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = f.Change(func(contents []byte) ([]byte, error) {
+		return contents, nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	after, err := os.ReadFile(f.ContentPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(after) != string(before) {
+		t.Errorf("file rewritten: %s, %s", before, after)
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	f := newFile(t)
+	if err := f.Write([]byte("secret: 1\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	t.Setenv("TEST_MASTER_KEY", GenerateKey())
+	if _, err := f.Read(); err == nil {
+		t.Error("expected error for the key of the environment")
+	}
+
+	t.Setenv("TEST_MASTER_KEY", key)
+	if _, err := f.Read(); err != nil {
+		t.Error(err)
+	}
+
+	// Like Rails, the variable is not stripped, but ignored when blank
+	t.Setenv("TEST_MASTER_KEY", key+"\n")
+	if _, err := f.Key(); !errors.Is(err, InvalidKeyLengthError) {
+		t.Errorf("expected InvalidKeyLengthError, got: %v", err)
+	}
+
+	t.Setenv("TEST_MASTER_KEY", " \n")
+	if _, err := f.Read(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKeyErrors(t *testing.T) {
+	f := newFile(t)
+	f.KeyPath = filepath.Join(filepath.Dir(f.KeyPath), "missing.key")
+
+	if key, err := f.Key(); key != "" || err != nil {
+		t.Errorf("unexpected key: %q, %v", key, err)
+	}
+	if err := f.Write([]byte("secret: 1\n")); !errors.Is(err, MissingKeyError) {
+		t.Errorf("expected MissingKeyError, got: %v", err)
+	}
+
+	f.RaiseIfMissingKey = true
+	if _, err := f.Key(); !errors.Is(err, MissingKeyError) {
+		t.Errorf("expected MissingKeyError, got: %v", err)
+	}
+
+	t.Setenv("TEST_MASTER_KEY", "abc")
+	if _, err := f.Key(); !errors.Is(err, InvalidKeyLengthError) {
+		t.Errorf("expected InvalidKeyLengthError, got: %v", err)
+	}
+
+	t.Setenv("TEST_MASTER_KEY", "zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz")
+	if _, err := f.Key(); !errors.Is(err, InvalidKeyError) {
+		t.Errorf("expected InvalidKeyError, got: %v", err)
+	}
+}
+
+func TestMissingContent(t *testing.T) {
+	if _, err := newFile(t).Read(); !errors.Is(err, MissingContentError) {
+		t.Errorf("expected MissingContentError, got: %v", err)
+	}
+
+	f := newFile(t)
+	if err := f.Write([]byte("secret: 1\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	f.KeyPath = filepath.Join(filepath.Dir(f.KeyPath), "missing.key")
+	if _, err := f.Read(); !errors.Is(err, MissingContentError) {
+		t.Errorf("expected MissingContentError, got: %v", err)
+	}
+
+	f.RaiseIfMissingKey = true
+	if _, err := f.Read(); !errors.Is(err, MissingKeyError) {
+		t.Errorf("expected MissingKeyError, got: %v", err)
+	}
+}
+
+func TestReadStripsContent(t *testing.T) {
+	f := newFile(t)
+	if err := f.Write([]byte("secret: 1\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	encrypted, err := os.ReadFile(f.ContentPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := os.WriteFile(f.ContentPath, append(encrypted, '\n'), 0644); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if contents, err := f.Read(); err != nil || string(contents) != "secret: 1\n" {
+		t.Errorf("unexpected contents: %q, %v", contents, err)
+	}
+}
@@ -0,0 +1,191 @@
+// Package globalid mirrors the globalid gem, which identifies records by
+// URIs like gid://app/Person/1 for ActiveJob and ActionText.
+package globalid
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CompositeModelIDMaxSize is the maximum number of parts of a composite id.
+const CompositeModelIDMaxSize = 20
+
+var (
+	InvalidGlobalIDError = errors.New("globalid: invalid GlobalID")
+	MissingModelIDError  = errors.New("globalid: missing model id")
+	InvalidAppError      = errors.New("globalid: invalid app name")
+)
+
+type GlobalID struct {
+	App       string
+	ModelName string
+	// ModelID has one part, or several for a composite primary key.
+	ModelID []string
+	Params  map[string]string
+}
+
+// New returns the GlobalID of a record, like GlobalID.create.
+func New(app, modelName string, modelID ...string) (*GlobalID, error) {
+	if err := ValidateApp(app); err != nil {
+		return nil, err
+	}
+
+	g := &GlobalID{App: app, ModelName: modelName, ModelID: modelID}
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// ValidateApp checks that app is a valid URI hostname, like
+// GlobalID.app=.
+func ValidateApp(app string) error {
+	u, err := url.Parse("gid://" + app + "/Model/1")
+	if err != nil || u.Hostname() != app || u.Port() != "" {
+		return fmt.Errorf("%w: %q", InvalidAppError, app)
+	}
+
+	return nil
+}
+
+// Parse parses a GlobalID URI, like GlobalID.parse.
+func Parse(gid string) (*GlobalID, error) {
+	u, err := url.Parse(gid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", InvalidGlobalIDError, err)
+	}
+	if u.Scheme != "gid" {
+		return nil, fmt.Errorf("%w: not a gid:// URI: %q", InvalidGlobalIDError, gid)
+	}
+	if u.Host == "" || u.User != nil || u.Port() != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("%w: %q", InvalidGlobalIDError, gid)
+	}
+
+	g := &GlobalID{App: u.Host}
+
+	// The model name and the escaped parts of the model id
+	parts := strings.Split(strings.TrimPrefix(u.EscapedPath(), "/"), "/")
+	g.ModelName = parts[0]
+	for _, part := range parts[1:] {
+		id, err := url.QueryUnescape(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", InvalidGlobalIDError, err)
+		}
+		g.ModelID = append(g.ModelID, id)
+	}
+
+	if u.RawQuery != "" {
+		query, err := url.ParseQuery(u.RawQuery)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", InvalidGlobalIDError, err)
+		}
+
+		g.Params = make(map[string]string, len(query))
+		for key, values := range query {
+			g.Params[key] = values[len(values)-1]
+		}
+	}
+
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+func (g *GlobalID) validate() error {
+	if g.ModelName == "" {
+		return fmt.Errorf("%w: expected a URI like gid://app/Person/1234", InvalidGlobalIDError)
+	}
+
+	if len(g.ModelID) == 0 {
+		return fmt.Errorf("%w: %s", MissingModelIDError, g.ModelName)
+	}
+	for _, id := range g.ModelID {
+		if strings.TrimSpace(id) == "" {
+			return fmt.Errorf("%w: %s", MissingModelIDError, g.ModelName)
+		}
+	}
+	if len(g.ModelID) > CompositeModelIDMaxSize {
+		return fmt.Errorf("%w: too many parts of model id", InvalidGlobalIDError)
+	}
+
+	return nil
+}
+
+// String returns the URI of the GlobalID. Params are sorted by key.
+func (g *GlobalID) String() string {
+	var b strings.Builder
+	b.WriteString("gid://")
+	b.WriteString(g.App)
+	b.WriteByte('/')
+	b.WriteString(g.ModelName)
+
+	for _, id := range g.ModelID {
+		b.WriteByte('/')
+		b.WriteString(url.QueryEscape(id))
+	}
+
+	if len(g.Params) > 0 {
+		keys := make([]string, 0, len(g.Params))
+		for key := range g.Params {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for i, key := range keys {
+			if i == 0 {
+				b.WriteByte('?')
+			} else {
+				b.WriteByte('&')
+			}
+			b.WriteString(encodeWWWFormComponent(key))
+			b.WriteByte('=')
+			b.WriteString(encodeWWWFormComponent(g.Params[key]))
+		}
+	}
+
+	return b.String()
+}
+
+// Param returns the URL-safe Base64 of the URI, like to_param, which is also
+// used by to_gid_param.
+func (g *GlobalID) Param() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(g.String()))
+}
+
+// ParseParam parses the Param of a GlobalID, like GlobalID.parse of an
+// encoded GlobalID.
+func ParseParam(param string) (*GlobalID, error) {
+	gid, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(param, "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", InvalidGlobalIDError, err)
+	}
+
+	return Parse(string(gid))
+}
+
+// encodeWWWFormComponent mirrors URI.encode_www_form_component, which keeps
+// *-._ and alphanumerics, and encodes spaces as +.
+func encodeWWWFormComponent(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '*', c == '-', c == '.', c == '_':
+			b.WriteByte(c)
+		case c == ' ':
+			b.WriteByte('+')
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
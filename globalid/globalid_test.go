@@ -0,0 +1,81 @@
+package globalid
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	for input, want := range map[string]*GlobalID{
+		"gid://bcx/Person/5":                  {App: "bcx", ModelName: "Person", ModelID: []string{"5"}},
+		"gid://my-app/Person/a%2Fb+c":         {App: "my-app", ModelName: "Person", ModelID: []string{"a/b c"}},
+		"gid://bcx/TravelRoute/tokyo/14":      {App: "bcx", ModelName: "TravelRoute", ModelID: []string{"tokyo", "14"}},
+		"gid://bcx/Person/5?database=replica": {App: "bcx", ModelName: "Person", ModelID: []string{"5"}, Params: map[string]string{"database": "replica"}},
+	} {
+		got, err := Parse(input)
+		if err != nil {
+			t.Errorf("input: %s; %v", input, err)
+			continue
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("input: %s; data mismatch (-want +got):\n%s", input, diff)
+		}
+		if got.String() != input {
+			t.Errorf("output mismatch: want %q; got %q", input, got.String())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for input, want := range map[string]error{
+		"http://bcx/Person/5": InvalidGlobalIDError,
+		"gid:///Person/5":     InvalidGlobalIDError,
+		"gid://bcx//5":        InvalidGlobalIDError,
+		"gid://bcx/Person":    MissingModelIDError,
+		"gid://bcx/Person/":   MissingModelIDError,
+		"gid://bcx/Person/%":  InvalidGlobalIDError,
+	} {
+		if _, err := Parse(input); !errors.Is(err, want) {
+			t.Errorf("input: %s; expected %v, got: %v", input, want, err)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	g, err := New("bcx", "Person", "5")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	g.Params = map[string]string{"note": "a b*~", "database": "replica"}
+	if want := "gid://bcx/Person/5?database=replica&note=a+b*%7E"; g.String() != want {
+		t.Errorf("output mismatch: want %q; got %q", want, g.String())
+	}
+
+	if _, err := New("b/cx", "Person", "5"); !errors.Is(err, InvalidAppError) {
+		t.Errorf("expected InvalidAppError, got: %v", err)
+	}
+	if _, err := New("bcx", "Person"); !errors.Is(err, MissingModelIDError) {
+		t.Errorf("expected MissingModelIDError, got: %v", err)
+	}
+}
+
+func TestParam(t *testing.T) {
+	g, _ := Parse("gid://bcx/Person/5")
+
+	if want := "Z2lkOi8vYmN4L1BlcnNvbi81"; g.Param() != want {
+		t.Errorf("want %q; got %q", want, g.Param())
+	}
+
+	got, err := ParseParam(g.Param())
+	if err != nil || got.String() != g.String() {
+		t.Errorf("unexpected result: %v, %v", got, err)
+	}
+
+	if _, err := ParseParam("!"); !errors.Is(err, InvalidGlobalIDError) {
+		t.Errorf("expected InvalidGlobalIDError, got: %v", err)
+	}
+}
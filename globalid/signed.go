@@ -0,0 +1,149 @@
+package globalid
+
+import (
+	"crypto/sha1"
+	"time"
+
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+const (
+	// DefaultPurpose is the purpose of SignedGlobalIDs without for:.
+	DefaultPurpose = "default"
+	// Salt derives the secret of SignedGlobalID.verifier from the key
+	// generator of a Rails application.
+	Salt = "signed_global_ids"
+)
+
+// NewVerifier returns a GlobalID::Verifier, a MessageVerifier signing with
+// SHA1 which encodes messages with padded URL-safe base64. Like Rails 7.1+
+// applications, it serializes with JSON and reads Marshal as a fallback.
+func NewVerifier(secret []byte) *verifier.Verifier {
+	serializer, err := codec.SerializerWithFallback("json_allow_marshal")
+	if err != nil {
+		panic(err)
+	}
+
+	msgCodec := codec.New(false, false).WithPaddedURLSafe().WithSerializer(serializer)
+
+	return verifier.New(msgCodec, sha1.New, secret)
+}
+
+// SignOptions are the options of to_sgid and GlobalID::Locator.locate_signed.
+type SignOptions struct {
+	// Purpose is the for: option, and defaults to DefaultPurpose.
+	Purpose   string
+	ExpiresAt *time.Time
+	ExpiresIn *time.Duration
+	// NoExpiry signs without expiry, like expires_in: nil. Otherwise, and
+	// without ExpiresAt or ExpiresIn, SignedGlobalIDs expire in one month,
+	// the default of config.global_id.expires_in.
+	NoExpiry bool
+}
+
+func (opt SignOptions) purpose() string {
+	if opt.Purpose == "" {
+		return DefaultPurpose
+	}
+
+	return opt.Purpose
+}
+
+// Signer mirrors SignedGlobalID, which signs the URI of a GlobalID with
+// SignedGlobalID.verifier, usually a verifier of NewVerifier.
+type Signer struct {
+	verifier *verifier.Verifier
+	now      func() time.Time
+}
+
+func NewSigner(v *verifier.Verifier) *Signer {
+	if v == nil {
+		panic("globalid: empty verifier")
+	}
+
+	return &Signer{verifier: v, now: time.Now}
+}
+
+// WithClock returns a copy of the signer which computes the default expiry
+// from now.
+func (s *Signer) WithClock(now func() time.Time) *Signer {
+	copied := *s
+	copied.now = now
+
+	return &copied
+}
+
+// Sign returns the SignedGlobalID of g, like to_sgid.
+func (s *Signer) Sign(g *GlobalID, opt SignOptions) (string, error) {
+	metadata := codec.MetadataOption{
+		Purpose:   opt.purpose(),
+		ExpiresAt: opt.ExpiresAt,
+		ExpiresIn: opt.ExpiresIn,
+	}
+	if !opt.NoExpiry && opt.ExpiresAt == nil && opt.ExpiresIn == nil {
+		expiresAt := addMonth(s.now())
+		metadata.ExpiresAt = &expiresAt
+	}
+
+	sgid, err := s.verifier.Generate(g.String(), metadata)
+	if err != nil {
+		return "", err
+	}
+
+	return string(sgid), nil
+}
+
+// Verify returns the GlobalID of sgid, like SignedGlobalID.parse. It also
+// accepts SignedGlobalIDs of globalid before 1.0, which embed their purpose
+// and expiry in the signed data.
+func (s *Signer) Verify(sgid string, opt SignOptions) (*GlobalID, error) {
+	var gid string
+	err := s.verifier.Verify([]byte(sgid), &gid, codec.MetadataOption{Purpose: opt.purpose()})
+	if err != nil {
+		var legacyErr error
+		if gid, legacyErr = s.verifyLegacy(sgid, opt); legacyErr != nil {
+			return nil, err
+		}
+	}
+
+	return Parse(gid)
+}
+
+type legacyMetadata struct {
+	GID       string `json:"gid"`
+	Purpose   string `json:"purpose"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+func (s *Signer) verifyLegacy(sgid string, opt SignOptions) (string, error) {
+	var metadata legacyMetadata
+	if err := s.verifier.Verify([]byte(sgid), &metadata, codec.MetadataOption{}); err != nil {
+		return "", err
+	}
+
+	if metadata.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339Nano, metadata.ExpiresAt)
+		if err != nil {
+			return "", err
+		}
+		if s.now().After(expiresAt) {
+			return "", codec.ExpiredError
+		}
+	}
+
+	if metadata.GID == "" || metadata.Purpose != opt.purpose() {
+		return "", codec.MismatchedPurposeError
+	}
+
+	return metadata.GID, nil
+}
+
+// addMonth mirrors 1.month.from_now, which clamps the day to the end of the
+// next month.
+func addMonth(t time.Time) time.Time {
+	year, month, day := t.Date()
+	lastDay := time.Date(year, month+2, 0, 0, 0, 0, 0, t.Location()).Day()
+
+	return time.Date(year, month+1, min(day, lastDay), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
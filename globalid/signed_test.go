@@ -0,0 +1,130 @@
+package globalid
+
+import (
+	"crypto/sha1"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+func testVerifier(now time.Time) *verifier.Verifier {
+	serializer, err := codec.SerializerWithFallback("json_allow_marshal")
+	if err != nil {
+		panic(err)
+	}
+
+	msgCodec := codec.New(false, false).WithPaddedURLSafe().WithSerializer(serializer).WithClock(func() time.Time { return now })
+
+	return verifier.New(msgCodec, sha1.New, []byte("secret"))
+}
+
+func TestSignedRoundTrip(t *testing.T) {
+	now := time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)
+	s := NewSigner(testVerifier(now)).WithClock(func() time.Time { return now })
+	g, _ := Parse("gid://bcx/Person/5")
+
+	sgid, err := s.Sign(g, SignOptions{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	got, err := s.Verify(sgid, SignOptions{})
+	if err != nil || got.String() != g.String() {
+		t.Errorf("unexpected result: %v, %v", got, err)
+	}
+
+	if _, err := s.Verify(sgid, SignOptions{Purpose: "login"}); !errors.Is(err, codec.MismatchedPurposeError) {
+		t.Errorf("expected MismatchedPurposeError, got: %v", err)
+	}
+
+	// 1.month.from_now of January 31 is February 29
+	for _, offset := range []time.Duration{29*24*time.Hour - time.Second, 29*24*time.Hour + time.Second} {
+		later := NewSigner(testVerifier(now.Add(offset)))
+		_, err := later.Verify(sgid, SignOptions{})
+		if expired := errors.Is(err, codec.ExpiredError); expired != (offset > 29*24*time.Hour) {
+			t.Errorf("offset: %v; unexpected result: %v", offset, err)
+		}
+	}
+}
+
+func TestSignedNoExpiry(t *testing.T) {
+	now := time.Now()
+	g, _ := Parse("gid://bcx/Person/5")
+
+	sgid, err := NewSigner(testVerifier(now)).Sign(g, SignOptions{Purpose: "login", NoExpiry: true})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	later := NewSigner(testVerifier(now.AddDate(10, 0, 0)))
+	if got, err := later.Verify(sgid, SignOptions{Purpose: "login"}); err != nil || got.String() != g.String() {
+		t.Errorf("unexpected result: %v, %v", got, err)
+	}
+}
+
+func TestSignedLegacy(t *testing.T) {
+	now := time.Now()
+	v := testVerifier(now)
+
+	sgid, err := v.Generate(map[string]any{
+		"gid":        "gid://bcx/Person/5",
+		"purpose":    "default",
+		"expires_at": now.Add(time.Hour).UTC().Format(time.RFC3339Nano),
+	}, codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s := NewSigner(v).WithClock(func() time.Time { return now })
+	if got, err := s.Verify(string(sgid), SignOptions{}); err != nil || got.String() != "gid://bcx/Person/5" {
+		t.Errorf("unexpected result: %v, %v", got, err)
+	}
+
+	if _, err := s.Verify(string(sgid), SignOptions{Purpose: "login"}); err == nil {
+		t.Error("expected error")
+	}
+
+	later := s.WithClock(func() time.Time { return now.Add(2 * time.Hour) })
+	if _, err := later.Verify(string(sgid), SignOptions{}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSignedRails(t *testing.T) {
+	sgid, err := os.ReadFile("testdata/TestSignedRails.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// The payload is encoded with "-" and padding
+	if payload, _, _ := strings.Cut(string(sgid), "--"); !strings.Contains(payload, "-") || !strings.HasSuffix(payload, "=") {
+		t.Errorf("unexpected payload: %s", payload)
+	}
+
+	s := NewSigner(NewVerifier([]byte("12345678")))
+	expiresAt := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	opt := SignOptions{Purpose: "a~~~", ExpiresAt: &expiresAt}
+
+	got, err := s.Verify(string(sgid), opt)
+	if err != nil || got.String() != "gid://bcx/Person/5" {
+		t.Errorf("unexpected result: %v, %v", got, err)
+	}
+
+	generated, err := s.Sign(got, opt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if generated != string(sgid) {
+		t.Errorf("output mismatch: want %q; got %q", sgid, generated)
+	}
+}
@@ -0,0 +1,84 @@
+// Package rotation mirrors ActiveSupport::Messages::RotationCoordinator, which
+// builds one message verifier or encryptor per salt out of a list of option
+// sets. The first option set is the primary config, the others are rotations.
+package rotation
+
+import (
+	"sync"
+)
+
+type Coordinator[O, T any] struct {
+	mu        sync.Mutex
+	build     func(salt string, options []O) T
+	rotations []func(salt string) (O, bool)
+	codecs    map[string]T
+}
+
+func New[O, T any](build func(salt string, options []O) T) *Coordinator[O, T] {
+	if build == nil {
+		panic("rotation: empty build func")
+	}
+
+	return &Coordinator[O, T]{
+		build:  build,
+		codecs: map[string]T{},
+	}
+}
+
+// Rotate appends an option set. fn may skip a salt by returning false.
+func (c *Coordinator[O, T]) Rotate(fn func(salt string) (O, bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.changingConfiguration()
+	c.rotations = append(c.rotations, fn)
+}
+
+// Prepend inserts an option set which becomes the primary config.
+func (c *Coordinator[O, T]) Prepend(fn func(salt string) (O, bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.changingConfiguration()
+	c.rotations = append([]func(salt string) (O, bool){fn}, c.rotations...)
+}
+
+// Get returns the codec for salt, building it on first use.
+func (c *Coordinator[O, T]) Get(salt string) T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if codec, ok := c.codecs[salt]; ok {
+		return codec
+	}
+
+	var options []O
+	for _, fn := range c.rotations {
+		if opts, ok := fn(salt); ok {
+			options = append(options, opts)
+		}
+	}
+	if len(options) == 0 {
+		panic("rotation: no options for salt " + salt)
+	}
+
+	codec := c.build(salt, options)
+	c.codecs[salt] = codec
+
+	return codec
+}
+
+// Set overrides the codec for salt.
+func (c *Coordinator[O, T]) Set(salt string, codec T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.codecs[salt] = codec
+}
+
+// Like Rails, the options cannot change once a codec has been handed out.
+func (c *Coordinator[O, T]) changingConfiguration() {
+	if len(c.codecs) > 0 {
+		panic("rotation: cannot change configuration after it has already been applied")
+	}
+}
@@ -0,0 +1,437 @@
+// Package rubyconv converts between decoded Ruby values and Go values for the
+// Marshal and MessagePack serializers.
+package rubyconv
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/atitan/activesupport-go/ruby"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	bigType  = reflect.TypeOf(big.Int{})
+	ratType  = reflect.TypeOf(big.Rat{})
+	hashType = reflect.TypeOf(ruby.Hash{})
+
+	anySliceType = reflect.TypeOf([]any(nil))
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Timer is implemented by decoded values which stand for a point in time but
+// are not a time.Time, such as dates.
+type Timer interface {
+	Time() time.Time
+}
+
+// Assign stores a decoded Ruby value into dst, the same way encoding/json
+// stores a decoded JSON value. Struct fields are matched by tag, then by
+// field name.
+func Assign(dst reflect.Value, src any, tag string) error {
+	if src == nil {
+		switch dst.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+			dst.SetZero()
+		}
+
+		return nil
+	}
+
+	if dst.Kind() == reflect.Interface {
+		// Like encoding/json, decode into the pointer held by the interface
+		if !dst.IsNil() {
+			if e := dst.Elem(); e.Kind() == reflect.Pointer && !e.IsNil() {
+				return Assign(e.Elem(), src, tag)
+			}
+		}
+
+		normalized := Normalize(src)
+		if !reflect.TypeOf(normalized).AssignableTo(dst.Type()) {
+			return typeError(src, dst.Type())
+		}
+
+		dst.Set(reflect.ValueOf(normalized))
+		return nil
+	}
+
+	if srcType := reflect.TypeOf(src); srcType.AssignableTo(dst.Type()) {
+		dst.Set(reflect.ValueOf(src))
+		return nil
+	}
+
+	if dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+
+		return Assign(dst.Elem(), src, tag)
+	}
+
+	if dst.CanAddr() && dst.Addr().Type().Implements(textUnmarshalerType) && dst.Type() != timeType {
+		if text, ok := stringOf(src); ok {
+			return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+		}
+	}
+
+	switch dst.Type() {
+	case timeType:
+		return assignTime(dst, src)
+	case bigType:
+		return assignBig(dst, src)
+	case ratType:
+		return assignRat(dst, src)
+	case hashType:
+		h, ok := src.(*ruby.Hash)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+
+		dst.Set(reflect.ValueOf(*h))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		s, ok := stringOf(src)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+
+		dst.SetString(s)
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := intOf(src)
+		if !ok || dst.OverflowInt(i) {
+			return typeError(src, dst.Type())
+		}
+
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, ok := uintOf(src)
+		if !ok || dst.OverflowUint(u) {
+			return typeError(src, dst.Type())
+		}
+
+		dst.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, ok := floatOf(src)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+
+		dst.SetFloat(f)
+	case reflect.Slice:
+		return assignSlice(dst, src, tag)
+	case reflect.Array:
+		return assignArray(dst, src, tag)
+	case reflect.Map:
+		return assignMap(dst, src, tag)
+	case reflect.Struct:
+		return assignStruct(dst, src, tag)
+	default:
+		return typeError(src, dst.Type())
+	}
+
+	return nil
+}
+
+// Normalize turns a decoded value into plain Go values suited for an `any`:
+// hashes become map[string]any when all keys are strings or symbols, and
+// map[any]any otherwise.
+func Normalize(src any) any {
+	switch v := src.(type) {
+	case *ruby.Hash:
+		return normalizeHash(v)
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			out[i] = Normalize(v[i])
+		}
+
+		return out
+	default:
+		return src
+	}
+}
+
+func normalizeHash(h *ruby.Hash) any {
+	stringKeys := true
+	for _, pair := range h.Pairs {
+		if _, ok := stringOf(pair.Key); !ok {
+			stringKeys = false
+			break
+		}
+	}
+
+	if stringKeys {
+		out := make(map[string]any, len(h.Pairs))
+		for _, pair := range h.Pairs {
+			key, _ := stringOf(pair.Key)
+			out[key] = Normalize(pair.Value)
+		}
+
+		return out
+	}
+
+	out := make(map[any]any, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		key := pair.Key
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			key = fmt.Sprint(key)
+		}
+
+		out[key] = Normalize(pair.Value)
+	}
+
+	return out
+}
+
+func assignTime(dst reflect.Value, src any) error {
+	switch v := src.(type) {
+	case Timer:
+		dst.Set(reflect.ValueOf(v.Time()))
+	default:
+		s, ok := stringOf(src)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+
+		dst.Set(reflect.ValueOf(t))
+	}
+
+	return nil
+}
+
+func assignBig(dst reflect.Value, src any) error {
+	switch v := src.(type) {
+	case *big.Int:
+		dst.Set(reflect.ValueOf(*new(big.Int).Set(v)))
+	case int64:
+		dst.Set(reflect.ValueOf(*big.NewInt(v)))
+	case uint64:
+		dst.Set(reflect.ValueOf(*new(big.Int).SetUint64(v)))
+	default:
+		return typeError(src, dst.Type())
+	}
+
+	return nil
+}
+
+func assignRat(dst reflect.Value, src any) error {
+	switch v := src.(type) {
+	case *big.Rat:
+		dst.Set(reflect.ValueOf(*new(big.Rat).Set(v)))
+	case *big.Int:
+		dst.Set(reflect.ValueOf(*new(big.Rat).SetInt(v)))
+	case int64:
+		dst.Set(reflect.ValueOf(*big.NewRat(v, 1)))
+	default:
+		return typeError(src, dst.Type())
+	}
+
+	return nil
+}
+
+func assignSlice(dst reflect.Value, src any, tag string) error {
+	if dst.Type().Elem().Kind() == reflect.Uint8 {
+		switch v := src.(type) {
+		case []byte:
+			dst.SetBytes(append([]byte(nil), v...))
+			return nil
+		case string:
+			dst.SetBytes([]byte(v))
+			return nil
+		}
+	}
+
+	if dst.Type().Elem() == reflect.TypeOf(ruby.Pair{}) {
+		h, ok := src.(*ruby.Hash)
+		if !ok {
+			return typeError(src, dst.Type())
+		}
+
+		dst.Set(reflect.ValueOf(append([]ruby.Pair(nil), h.Pairs...)))
+		return nil
+	}
+
+	elems, ok := elemsOf(src)
+	if !ok {
+		return typeError(src, dst.Type())
+	}
+
+	out := reflect.MakeSlice(dst.Type(), len(elems), len(elems))
+	for i := range elems {
+		if err := Assign(out.Index(i), elems[i], tag); err != nil {
+			return err
+		}
+	}
+
+	dst.Set(out)
+	return nil
+}
+
+func assignArray(dst reflect.Value, src any, tag string) error {
+	elems, ok := elemsOf(src)
+	if !ok || len(elems) > dst.Len() {
+		return typeError(src, dst.Type())
+	}
+
+	for i := range elems {
+		if err := Assign(dst.Index(i), elems[i], tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func assignMap(dst reflect.Value, src any, tag string) error {
+	h, ok := src.(*ruby.Hash)
+	if !ok {
+		return typeError(src, dst.Type())
+	}
+
+	if dst.IsNil() {
+		dst.Set(reflect.MakeMapWithSize(dst.Type(), len(h.Pairs)))
+	}
+
+	for _, pair := range h.Pairs {
+		key := reflect.New(dst.Type().Key()).Elem()
+		if err := Assign(key, pair.Key, tag); err != nil {
+			return err
+		}
+
+		value := reflect.New(dst.Type().Elem()).Elem()
+		if err := Assign(value, pair.Value, tag); err != nil {
+			return err
+		}
+
+		dst.SetMapIndex(key, value)
+	}
+
+	return nil
+}
+
+func assignStruct(dst reflect.Value, src any, tag string) error {
+	h, ok := src.(*ruby.Hash)
+	if !ok {
+		return typeError(src, dst.Type())
+	}
+
+	fields := Fields(dst.Type(), tag)
+
+	for _, pair := range h.Pairs {
+		key, ok := stringOf(pair.Key)
+		if !ok {
+			continue
+		}
+
+		field, ok := lookupField(fields, key)
+		if !ok {
+			continue
+		}
+
+		if err := Assign(dst.FieldByIndex(field.Index), pair.Value, tag); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func stringOf(src any) (string, bool) {
+	switch v := src.(type) {
+	case string:
+		return v, true
+	case ruby.Symbol:
+		return string(v), true
+	case []byte:
+		return string(v), true
+	}
+
+	// Named strings like time zone names or paths
+	if rv := reflect.ValueOf(src); rv.Kind() == reflect.String {
+		return rv.String(), true
+	}
+
+	return "", false
+}
+
+// elemsOf returns the elements of an array, including named ones like sets.
+func elemsOf(src any) ([]any, bool) {
+	if elems, ok := src.([]any); ok {
+		return elems, true
+	}
+
+	if rv := reflect.ValueOf(src); rv.Kind() == reflect.Slice && rv.Type().ConvertibleTo(anySliceType) {
+		return rv.Convert(anySliceType).Interface().([]any), true
+	}
+
+	return nil, false
+}
+
+func intOf(src any) (int64, bool) {
+	switch v := src.(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case *big.Int:
+		return v.Int64(), v.IsInt64()
+	}
+
+	return 0, false
+}
+
+func uintOf(src any) (uint64, bool) {
+	switch v := src.(type) {
+	case int64:
+		return uint64(v), v >= 0
+	case uint64:
+		return v, true
+	case *big.Int:
+		return v.Uint64(), v.IsUint64()
+	}
+
+	return 0, false
+}
+
+func floatOf(src any) (float64, bool) {
+	switch v := src.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f, true
+	}
+
+	return 0, false
+}
+
+func typeError(src any, t reflect.Type) error {
+	return fmt.Errorf("cannot unmarshal %T into Go value of type %s", src, t)
+}
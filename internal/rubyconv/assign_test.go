@@ -0,0 +1,77 @@
+package rubyconv
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/atitan/activesupport-go/ruby"
+	"github.com/google/go-cmp/cmp"
+)
+
+type tagged struct {
+	Name    string `marshal:"name"`
+	Count   int    `json:"count"`
+	Skipped string `json:"-"`
+	Plain   bool
+}
+
+func TestAssignStruct(t *testing.T) {
+	src := ruby.NewHash(
+		ruby.Pair{Key: ruby.Symbol("name"), Value: "pizza"},
+		ruby.Pair{Key: "count", Value: int64(3)},
+		ruby.Pair{Key: "Skipped", Value: "ignored"},
+		ruby.Pair{Key: "plain", Value: true},
+	)
+
+	var data tagged
+	if err := Assign(reflect.ValueOf(&data).Elem(), src, "marshal"); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(tagged{Name: "pizza", Count: 3, Plain: true}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAssignInterfaceHoldingPointer(t *testing.T) {
+	var data int
+	var holder any = &data
+
+	if err := Assign(reflect.ValueOf(&holder).Elem(), int64(42), "marshal"); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != 42 {
+		t.Errorf("data mismatch: %d", data)
+	}
+}
+
+func TestAssignNormalize(t *testing.T) {
+	src := []any{
+		ruby.NewHash(ruby.Pair{Key: ruby.Symbol("a"), Value: int64(1)}),
+		ruby.NewHash(ruby.Pair{Key: int64(1), Value: "b"}),
+	}
+
+	var data any
+	if err := Assign(reflect.ValueOf(&data).Elem(), src, "marshal"); err != nil {
+		t.Error(err)
+		return
+	}
+	want := []any{map[string]any{"a": int64(1)}, map[any]any{int64(1): "b"}}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAssignOverflow(t *testing.T) {
+	var data int8
+	if err := Assign(reflect.ValueOf(&data).Elem(), int64(300), "marshal"); err == nil {
+		t.Errorf("expected overflow error")
+	}
+
+	var large big.Int
+	if err := Assign(reflect.ValueOf(&large).Elem(), int64(300), "marshal"); err != nil {
+		t.Error(err)
+	}
+}
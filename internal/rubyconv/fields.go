@@ -0,0 +1,75 @@
+package rubyconv
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type Field struct {
+	Name      string
+	Index     []int
+	OmitEmpty bool
+}
+
+type fieldsKey struct {
+	t   reflect.Type
+	tag string
+}
+
+var fieldsCache sync.Map
+
+// Fields lists the serializable fields of a struct type. Names come from the
+// given tag, falling back to the json tag and then to the field name.
+func Fields(t reflect.Type, tag string) []Field {
+	key := fieldsKey{t: t, tag: tag}
+	if cached, ok := fieldsCache.Load(key); ok {
+		return cached.([]Field)
+	}
+
+	fields := make([]Field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		value, ok := sf.Tag.Lookup(tag)
+		if !ok {
+			value = sf.Tag.Get("json")
+		}
+		if value == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(value, ",")
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields = append(fields, Field{
+			Name:      name,
+			Index:     sf.Index,
+			OmitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+
+	fieldsCache.Store(key, fields)
+	return fields
+}
+
+func lookupField(fields []Field, name string) (Field, bool) {
+	for _, field := range fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+
+	for _, field := range fields {
+		if strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+
+	return Field{}, false
+}
@@ -0,0 +1,637 @@
+// Package yaml parses the subset of YAML found in Rails credentials: block
+// mappings and sequences, flow collections on a single line, quoted and plain
+// scalars, and literal or folded block scalars. Anchors, tags and multi-line
+// flow collections are not supported.
+//
+// Mappings decode to map[string]any, sequences to []any, and scalars to
+// string, int64, float64, bool or nil following the YAML 1.1 rules of Psych.
+// Dates and times are left as strings.
+package yaml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var SyntaxError = errors.New("yaml: syntax error")
+
+type line struct {
+	number int
+	indent int
+	text   string // without indentation
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+// Unmarshal parses a single YAML document. An empty document gives nil.
+func Unmarshal(data []byte) (any, error) {
+	p := &parser{}
+
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, p.errorAt(i+1, "tab indentation")
+		}
+
+		p.lines = append(p.lines, line{number: i + 1, indent: len(raw) - len(text), text: text})
+	}
+
+	p.skipBlank()
+	if p.pos < len(p.lines) && p.lines[p.pos].indent == 0 && isDocumentMarker(p.lines[p.pos].text, "---") {
+		rest := strings.TrimSpace(p.lines[p.pos].text[3:])
+		if rest != "" && !strings.HasPrefix(rest, "#") {
+			p.lines[p.pos].indent = 4
+			p.lines[p.pos].text = rest
+		} else {
+			p.pos++
+		}
+	}
+
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+
+	value, err := p.parseNode(p.lines[p.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+	if p.pos < len(p.lines) && !isDocumentMarker(p.lines[p.pos].text, "...") {
+		return nil, p.errorAt(p.lines[p.pos].number, "unexpected content")
+	}
+
+	return value, nil
+}
+
+func isDocumentMarker(text, marker string) bool {
+	return strings.HasPrefix(text, marker) && (len(text) == 3 || text[3] == ' ')
+}
+
+func (p *parser) errorAt(number int, msg string) error {
+	return fmt.Errorf("%w: line %d: %s", SyntaxError, number, msg)
+}
+
+// skipBlank moves past empty and comment-only lines.
+func (p *parser) skipBlank() {
+	for p.pos < len(p.lines) {
+		text := p.lines[p.pos].text
+		if text != "" && !strings.HasPrefix(text, "#") {
+			return
+		}
+		p.pos++
+	}
+}
+
+// parseNode parses the block node starting at the current line, which is
+// indented by indent.
+func (p *parser) parseNode(indent int) (any, error) {
+	l := p.lines[p.pos]
+
+	if isSequenceEntry(l.text) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitMappingEntry(l.text); ok {
+		return p.parseMapping(indent)
+	}
+
+	p.pos++
+	return parseInline(l.text, l.number)
+}
+
+func isSequenceEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *parser) parseSequence(indent int) (any, error) {
+	seq := []any{}
+
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return seq, nil
+		}
+
+		l := p.lines[p.pos]
+		if l.indent < indent || (l.indent == indent && !isSequenceEntry(l.text)) {
+			return seq, nil
+		}
+		if l.indent > indent {
+			return nil, p.errorAt(l.number, "bad sequence indentation")
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		value, err := p.parseEntryValue(indent, indent+len(l.text)-len(rest), rest, false)
+		if err != nil {
+			return nil, err
+		}
+
+		seq = append(seq, value)
+	}
+}
+
+func (p *parser) parseMapping(indent int) (any, error) {
+	m := map[string]any{}
+
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return m, nil
+		}
+
+		l := p.lines[p.pos]
+		if l.indent < indent || (l.indent == indent && isSequenceEntry(l.text)) {
+			return m, nil
+		}
+		if l.indent > indent {
+			return nil, p.errorAt(l.number, "bad mapping indentation")
+		}
+
+		rawKey, rest, ok := splitMappingEntry(l.text)
+		if !ok {
+			return nil, p.errorAt(l.number, "expected a mapping key")
+		}
+
+		key, err := parseKey(rawKey, l.number)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m[key]; ok {
+			return nil, p.errorAt(l.number, "duplicate key "+key)
+		}
+
+		value, err := p.parseEntryValue(indent, indent+len(l.text)-len(rest), rest, true)
+		if err != nil {
+			return nil, err
+		}
+
+		m[key] = value
+	}
+}
+
+// parseEntryValue parses what follows a sequence dash or a mapping key on the
+// current line. rest starts at column.
+func (p *parser) parseEntryValue(indent, column int, rest string, inMapping bool) (any, error) {
+	l := p.lines[p.pos]
+	rest = stripComment(rest)
+
+	switch {
+	case rest == "":
+		p.pos++
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			return nil, nil
+		}
+
+		next := p.lines[p.pos]
+		// A sequence may sit at the indentation of its mapping key
+		if next.indent > indent || (inMapping && next.indent == indent && isSequenceEntry(next.text)) {
+			return p.parseNode(next.indent)
+		}
+
+		return nil, nil
+	case rest[0] == '|' || rest[0] == '>':
+		p.pos++
+		return p.parseBlockScalar(indent, rest, l.number)
+	case !inMapping && (isSequenceEntry(rest) || hasMappingEntry(rest)):
+		// Compact nested collection like "- key: value" or "- - value"
+		p.lines[p.pos].indent = column
+		p.lines[p.pos].text = rest
+		return p.parseNode(column)
+	default:
+		p.pos++
+		return parseInline(rest, l.number)
+	}
+}
+
+func hasMappingEntry(text string) bool {
+	_, _, ok := splitMappingEntry(text)
+	return ok
+}
+
+// splitMappingEntry splits "key: value" outside of quotes and flow
+// collections. The value keeps its leading spaces trimmed.
+func splitMappingEntry(text string) (key, rest string, ok bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' || text[0] == '#' {
+		return "", "", false
+	}
+
+	start := 0
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 {
+			return "", "", false
+		}
+		start = end + 1
+	}
+
+	for i := start; i < len(text); i++ {
+		if text[i] == '#' && i > 0 && text[i-1] == ' ' {
+			return "", "", false
+		}
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimLeft(text[i+1:], " "), true
+		}
+	}
+
+	return "", "", false
+}
+
+// closingQuote returns the index of the quote closing the string opening
+// text, or -1.
+func closingQuote(text string) int {
+	quote := text[0]
+
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+
+	return -1
+}
+
+func stripComment(text string) string {
+	if strings.HasPrefix(text, "#") {
+		return ""
+	}
+
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == 0 && (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [{,", text[i-1]) >= 0):
+			quote = c
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && c == '#' && text[i-1] == ' ':
+			return strings.TrimRight(text[:i], " ")
+		}
+	}
+
+	return strings.TrimRight(text, " ")
+}
+
+func parseKey(raw string, number int) (string, error) {
+	key, err := parseInline(raw, number)
+	if err != nil {
+		return "", err
+	}
+
+	switch key := key.(type) {
+	case string:
+		// Rails symbolizes the keys anyway
+		return strings.TrimPrefix(key, ":"), nil
+	case nil:
+		return "", nil
+	case map[string]any, []any:
+		return "", fmt.Errorf("%w: line %d: complex mapping key", SyntaxError, number)
+	default:
+		return raw, nil
+	}
+}
+
+// parseBlockScalar parses a literal (|) or folded (>) block scalar whose
+// header is header.
+func (p *parser) parseBlockScalar(parentIndent int, header string, number int) (any, error) {
+	folded := header[0] == '>'
+	chomping := byte(0)
+	contentIndent := 0
+
+	for _, c := range []byte(header[1:]) {
+		switch {
+		case c == '-' || c == '+':
+			chomping = c
+		case c >= '1' && c <= '9':
+			contentIndent = parentIndent + int(c-'0')
+		default:
+			return nil, p.errorAt(number, "bad block scalar header")
+		}
+	}
+
+	var lines []string
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := p.lines[p.pos]
+		if l.text == "" {
+			lines = append(lines, "")
+			continue
+		}
+
+		if contentIndent == 0 {
+			contentIndent = l.indent
+		}
+		if l.indent < contentIndent || l.indent <= parentIndent {
+			break
+		}
+
+		lines = append(lines, strings.Repeat(" ", l.indent-contentIndent)+l.text)
+	}
+
+	// Trailing blank lines only matter to chomping
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var b strings.Builder
+	for i, text := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			switch {
+			case !folded:
+				b.WriteByte('\n')
+			case text != "" && prev != "" && text[0] != ' ' && prev[0] != ' ':
+				b.WriteByte(' ')
+			case text == "" && prev != "" && prev[0] != ' ':
+				// The line break folds into the empty lines which follow
+			default:
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(text)
+	}
+
+	switch {
+	case len(lines) == 0:
+		if chomping == '+' {
+			return strings.Repeat("\n", trailing), nil
+		}
+		return "", nil
+	case chomping == '-':
+	case chomping == '+':
+		b.WriteString(strings.Repeat("\n", trailing+1))
+	default:
+		b.WriteByte('\n')
+	}
+
+	return b.String(), nil
+}
+
+// parseInline parses a scalar or a flow collection which fits on one line.
+func parseInline(text string, number int) (any, error) {
+	fp := &flowParser{text: stripComment(text), number: number}
+
+	value, err := fp.parseValue(false)
+	if err != nil {
+		return nil, err
+	}
+
+	fp.skipSpaces()
+	if fp.pos < len(fp.text) {
+		return nil, fp.errorf("unexpected %q", fp.text[fp.pos:])
+	}
+
+	return value, nil
+}
+
+type flowParser struct {
+	text   string
+	pos    int
+	number int
+}
+
+func (fp *flowParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: line %d: %s", SyntaxError, fp.number, fmt.Sprintf(format, args...))
+}
+
+func (fp *flowParser) skipSpaces() {
+	for fp.pos < len(fp.text) && fp.text[fp.pos] == ' ' {
+		fp.pos++
+	}
+}
+
+func (fp *flowParser) parseValue(inFlow bool) (any, error) {
+	fp.skipSpaces()
+	if fp.pos >= len(fp.text) {
+		return nil, nil
+	}
+
+	switch c := fp.text[fp.pos]; c {
+	case '[':
+		return fp.parseFlowSequence()
+	case '{':
+		return fp.parseFlowMapping()
+	case '"', '\'':
+		end := closingQuote(fp.text[fp.pos:])
+		if end < 0 {
+			return nil, fp.errorf("unterminated string")
+		}
+
+		quoted := fp.text[fp.pos+1 : fp.pos+end]
+		fp.pos += end + 1
+		if c == '\'' {
+			return strings.ReplaceAll(quoted, "''", "'"), nil
+		}
+
+		return unescape(quoted, fp)
+	case '&', '*', '!':
+		return nil, fp.errorf("anchors, aliases and tags are not supported")
+	}
+
+	start := fp.pos
+	for fp.pos < len(fp.text) {
+		c := fp.text[fp.pos]
+		if inFlow && (c == ',' || c == ']' || c == '}' || (c == ':' && fp.pos+1 < len(fp.text) && fp.text[fp.pos+1] == ' ')) {
+			break
+		}
+		fp.pos++
+	}
+
+	return resolvePlain(strings.TrimSpace(fp.text[start:fp.pos])), nil
+}
+
+func (fp *flowParser) parseFlowSequence() (any, error) {
+	fp.pos++
+	seq := []any{}
+
+	for {
+		fp.skipSpaces()
+		if fp.pos >= len(fp.text) {
+			return nil, fp.errorf("unterminated flow sequence")
+		}
+		if fp.text[fp.pos] == ']' {
+			fp.pos++
+			return seq, nil
+		}
+
+		value, err := fp.parseValue(true)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, value)
+
+		if err := fp.parseSeparator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (fp *flowParser) parseFlowMapping() (any, error) {
+	fp.pos++
+	m := map[string]any{}
+
+	for {
+		fp.skipSpaces()
+		if fp.pos >= len(fp.text) {
+			return nil, fp.errorf("unterminated flow mapping")
+		}
+		if fp.text[fp.pos] == '}' {
+			fp.pos++
+			return m, nil
+		}
+
+		key, err := fp.parseValue(true)
+		if err != nil {
+			return nil, err
+		}
+		keyString, ok := key.(string)
+		if !ok {
+			keyString = fmt.Sprint(key)
+		}
+
+		fp.skipSpaces()
+		var value any
+		if fp.pos < len(fp.text) && fp.text[fp.pos] == ':' {
+			fp.pos++
+			if value, err = fp.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+		m[strings.TrimPrefix(keyString, ":")] = value
+
+		if err := fp.parseSeparator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseSeparator consumes a comma, leaving a closing bracket for the caller.
+func (fp *flowParser) parseSeparator(closing byte) error {
+	fp.skipSpaces()
+	switch {
+	case fp.pos >= len(fp.text):
+		return fp.errorf("unterminated flow collection")
+	case fp.text[fp.pos] == ',':
+		fp.pos++
+		return nil
+	case fp.text[fp.pos] == closing:
+		return nil
+	default:
+		return fp.errorf("expected , or %c", closing)
+	}
+}
+
+var escapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': " ", 'L': " ", 'P': " ",
+}
+
+func unescape(quoted string, fp *flowParser) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(quoted); i++ {
+		if quoted[i] != '\\' {
+			b.WriteByte(quoted[i])
+			continue
+		}
+
+		i++
+		if i >= len(quoted) {
+			return "", fp.errorf("bad escape")
+		}
+
+		if s, ok := escapes[quoted[i]]; ok {
+			b.WriteString(s)
+			continue
+		}
+
+		size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[quoted[i]]
+		if size == 0 || i+size >= len(quoted) {
+			return "", fp.errorf("bad escape")
+		}
+
+		code, err := strconv.ParseUint(quoted[i+1:i+1+size], 16, 32)
+		if err != nil {
+			return "", fp.errorf("bad escape")
+		}
+		b.WriteRune(rune(code))
+		i += size
+	}
+
+	return b.String(), nil
+}
+
+// resolvePlain applies the YAML 1.1 implicit types Psych recognizes.
+func resolvePlain(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE", "yes", "Yes", "YES", "on", "On", "ON":
+		return true
+	case "false", "False", "FALSE", "no", "No", "NO", "off", "Off", "OFF":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+
+	if i, ok := parseInt(s); ok {
+		return i
+	}
+
+	// Like Psych, floats need a dot, so "1e3" stays a string
+	if strings.Contains(s, ".") && !strings.ContainsAny(s, "xXpP") {
+		if f, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), 64); err == nil {
+			return f
+		}
+	}
+
+	return s
+}
+
+func parseInt(s string) (int64, bool) {
+	digits := strings.ReplaceAll(s, "_", "")
+	sign := ""
+	if digits != "" && (digits[0] == '-' || digits[0] == '+') {
+		sign, digits = digits[:1], digits[1:]
+	}
+	if digits == "" || digits[0] < '0' || digits[0] > '9' {
+		return 0, false
+	}
+
+	base := 10
+	switch {
+	case strings.HasPrefix(digits, "0x"):
+		base, digits = 16, digits[2:]
+	case strings.HasPrefix(digits, "0b"):
+		base, digits = 2, digits[2:]
+	case len(digits) > 1 && digits[0] == '0':
+		base, digits = 8, digits[1:]
+	}
+
+	i, err := strconv.ParseInt(sign+digits, base, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return i, true
+}
@@ -0,0 +1,74 @@
+package keygenerator
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Generator derives keys from a salt. Both KeyGenerator and
+// CachingKeyGenerator implement it.
+type Generator interface {
+	GenerateKey(salt []byte, keyLen int) []byte
+}
+
+type cacheKey struct {
+	salt   string
+	keyLen int
+}
+
+type cacheEntry struct {
+	once sync.Once
+	key  []byte
+}
+
+// CachingKeyGenerator mirrors ActiveSupport::CachingKeyGenerator. It derives
+// each (salt, keyLen) pair only once, even when asked concurrently.
+type CachingKeyGenerator struct {
+	generator Generator
+	mu        sync.Mutex
+	cache     map[cacheKey]*cacheEntry
+}
+
+func NewCaching(generator Generator) *CachingKeyGenerator {
+	if generator == nil {
+		panic("keygenerator: empty generator")
+	}
+
+	return &CachingKeyGenerator{
+		generator: generator,
+		cache:     map[cacheKey]*cacheEntry{},
+	}
+}
+
+func (c *CachingKeyGenerator) GenerateKey(salt []byte, keyLen int) []byte {
+	c.mu.Lock()
+	k := cacheKey{salt: string(salt), keyLen: keyLen}
+	entry, ok := c.cache[k]
+	if !ok {
+		entry = &cacheEntry{}
+		c.cache[k] = entry
+	}
+	c.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.key = c.generator.GenerateKey(salt, keyLen)
+	})
+
+	// Callers may modify the key, so never hand out the cached one
+	return slices.Clone(entry.key)
+}
+
+// Precompute derives the keys of salts ahead of time, e.g. at startup. It
+// stops early with the error of ctx once ctx is done.
+func (c *CachingKeyGenerator) Precompute(ctx context.Context, keyLen int, salts ...[]byte) error {
+	for _, salt := range salts {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		c.GenerateKey(salt, keyLen)
+	}
+
+	return nil
+}
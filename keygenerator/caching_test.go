@@ -0,0 +1,81 @@
+package keygenerator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type countingGenerator struct {
+	calls atomic.Int32
+}
+
+func (g *countingGenerator) GenerateKey(salt []byte, keyLen int) []byte {
+	g.calls.Add(1)
+	return bytes.Repeat(salt[:1], keyLen)
+}
+
+func TestCachingGenerateKey(t *testing.T) {
+	password := []byte("4aa19bef10a27fd29e09058b10e8c279cd0b3ecc7791ee527d8d02de71b1861bd259c3d03da8b89059eb8f2e0453aebdc17659e9eaf1aeefc8858c5a0b051bbf")
+	k := NewCaching(New(password, 1000, sha256.New))
+
+	out := k.GenerateKey([]byte("this is a salt"), 16)
+	expected := []byte{71, 181, 67, 190, 212, 199, 249, 78, 109, 170, 64, 46, 89, 172, 166, 67}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("data mismatch: %q, %q", out, expected)
+	}
+
+	out[0] = 0
+	if out := k.GenerateKey([]byte("this is a salt"), 16); !bytes.Equal(out, expected) {
+		t.Errorf("cached key modified: %q", out)
+	}
+}
+
+func TestCachingConcurrent(t *testing.T) {
+	g := &countingGenerator{}
+	k := NewCaching(g)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			k.GenerateKey([]byte("a"), 32)
+			k.GenerateKey([]byte("a"), 64)
+		}()
+	}
+	wg.Wait()
+
+	if calls := g.calls.Load(); calls != 2 {
+		t.Errorf("expected 2 derivations, got %d", calls)
+	}
+}
+
+func TestCachingPrecompute(t *testing.T) {
+	g := &countingGenerator{}
+	k := NewCaching(g)
+
+	if err := k.Precompute(context.Background(), 32, []byte("a"), []byte("b")); err != nil {
+		t.Error(err)
+		return
+	}
+	k.GenerateKey([]byte("a"), 32)
+	k.GenerateKey([]byte("b"), 32)
+
+	if calls := g.calls.Load(); calls != 2 {
+		t.Errorf("expected 2 derivations, got %d", calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := k.Precompute(ctx, 32, []byte("c")); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+	if calls := g.calls.Load(); calls != 2 {
+		t.Errorf("expected no derivation after cancel, got %d", calls)
+	}
+}
@@ -0,0 +1,601 @@
+package marshal
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atitan/activesupport-go/ruby"
+)
+
+const (
+	typeNil        = '0'
+	typeTrue       = 'T'
+	typeFalse      = 'F'
+	typeFixnum     = 'i'
+	typeExtended   = 'e'
+	typeUClass     = 'C'
+	typeObject     = 'o'
+	typeData       = 'd'
+	typeUserDef    = 'u'
+	typeUsrMarshal = 'U'
+	typeFloat      = 'f'
+	typeBignum     = 'l'
+	typeString     = '"'
+	typeRegexp     = '/'
+	typeArray      = '['
+	typeHash       = '{'
+	typeHashDef    = '}'
+	typeStruct     = 'S'
+	typeModuleOld  = 'M'
+	typeClass      = 'c'
+	typeModule     = 'm'
+	typeSymbol     = ':'
+	typeSymlink    = ';'
+	typeIvar       = 'I'
+	typeLink       = '@'
+)
+
+// pending marks an object table entry whose value is still being decoded.
+type pending struct{}
+
+type decoder struct {
+	data    []byte
+	pos     int
+	symbols []ruby.Symbol
+	objects []any
+}
+
+func (d *decoder) readByte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, InvalidFormatError
+	}
+
+	b := d.data[d.pos]
+	d.pos++
+
+	return b, nil
+}
+
+// readLong reads the variable length integer encoding of w_long.
+func (d *decoder) readLong() (int, error) {
+	b, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	c := int(int8(b))
+	switch {
+	case c == 0:
+		return 0, nil
+	case c > 4:
+		return c - 5, nil
+	case c < -4:
+		return c + 5, nil
+	case c > 0:
+		x := 0
+		for i := 0; i < c; i++ {
+			b, err := d.readByte()
+			if err != nil {
+				return 0, err
+			}
+
+			x |= int(b) << (8 * i)
+		}
+
+		return x, nil
+	default:
+		x := -1
+		for i := 0; i < -c; i++ {
+			b, err := d.readByte()
+			if err != nil {
+				return 0, err
+			}
+
+			x &= ^(0xff << (8 * i))
+			x |= int(b) << (8 * i)
+		}
+
+		return x, nil
+	}
+}
+
+func (d *decoder) readBytes() ([]byte, error) {
+	n, err := d.readLong()
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, InvalidFormatError
+	}
+
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+
+	return b, nil
+}
+
+func (d *decoder) readSymbol() (ruby.Symbol, error) {
+	t, err := d.readByte()
+	if err != nil {
+		return "", err
+	}
+
+	ivars := false
+	if t == typeIvar {
+		ivars = true
+		if t, err = d.readByte(); err != nil {
+			return "", err
+		}
+	}
+
+	switch t {
+	case typeSymbol:
+		return d.readSymbolBody(ivars)
+	case typeSymlink:
+		return d.readSymlink()
+	}
+
+	return "", InvalidFormatError
+}
+
+func (d *decoder) readSymbolBody(ivars bool) (ruby.Symbol, error) {
+	name, err := d.readBytes()
+	if err != nil {
+		return "", err
+	}
+
+	sym := ruby.Symbol(name)
+	d.symbols = append(d.symbols, sym)
+
+	// Symbol encoding is irrelevant in Go
+	if ivars {
+		if _, err := d.readIvars(); err != nil {
+			return "", err
+		}
+	}
+
+	return sym, nil
+}
+
+func (d *decoder) readSymlink() (ruby.Symbol, error) {
+	idx, err := d.readLong()
+	if err != nil {
+		return "", err
+	}
+
+	if idx < 0 || idx >= len(d.symbols) {
+		return "", InvalidFormatError
+	}
+
+	return d.symbols[idx], nil
+}
+
+func (d *decoder) readIvars() (map[string]any, error) {
+	n, err := d.readLong()
+	if err != nil {
+		return nil, err
+	}
+
+	// Like hashes, every pair takes at least two bytes
+	if n < 0 || n*2 > len(d.data)-d.pos {
+		return nil, InvalidFormatError
+	}
+
+	ivars := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		key, err := d.readSymbol()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := d.readValue(false)
+		if err != nil {
+			return nil, err
+		}
+
+		ivars[string(key)] = value
+	}
+
+	return ivars, nil
+}
+
+func (d *decoder) reserve() int {
+	d.objects = append(d.objects, pending{})
+
+	return len(d.objects) - 1
+}
+
+func (d *decoder) register(v any) any {
+	d.objects = append(d.objects, v)
+
+	return v
+}
+
+// readValue reads an object. When ivars is set the object was wrapped in an
+// 'I' entry, and its instance variables follow the object itself.
+func (d *decoder) readValue(ivars bool) (any, error) {
+	t, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	// These types read their own instance variables, such as the encoding of
+	// strings, in the order Ruby registers them
+	switch t {
+	case typeIvar:
+		return d.readValue(true)
+	case typeSymbol:
+		return d.readSymbolBody(ivars)
+	case typeExtended:
+		if _, err := d.readSymbol(); err != nil {
+			return nil, err
+		}
+
+		return d.readValue(ivars)
+	case typeUClass:
+		// Subclasses of String, Array and Hash, like HashWithIndifferentAccess,
+		// are surfaced as their base type
+		if _, err := d.readSymbol(); err != nil {
+			return nil, err
+		}
+
+		return d.readValue(ivars)
+	case typeString:
+		return d.readString(ivars)
+	case typeRegexp:
+		return d.readRegexp(ivars)
+	case typeUserDef:
+		return d.readUserDefined(ivars)
+	}
+
+	v, err := d.readPlainValue(t)
+	if err != nil || !ivars {
+		return v, err
+	}
+
+	// Instance variables of other objects, like an Array with @ivars, are
+	// skipped
+	if _, err := d.readIvars(); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (d *decoder) readPlainValue(t byte) (any, error) {
+	switch t {
+	case typeNil:
+		return nil, nil
+	case typeTrue:
+		return true, nil
+	case typeFalse:
+		return false, nil
+	case typeFixnum:
+		n, err := d.readLong()
+		return int64(n), err
+	case typeSymlink:
+		return d.readSymlink()
+	case typeLink:
+		idx, err := d.readLong()
+		if err != nil {
+			return nil, err
+		}
+
+		if idx < 0 || idx >= len(d.objects) {
+			return nil, InvalidFormatError
+		}
+
+		if _, ok := d.objects[idx].(pending); ok {
+			return nil, RecursiveLinkError
+		}
+
+		return d.objects[idx], nil
+	case typeFloat:
+		return d.readFloat()
+	case typeBignum:
+		return d.readBignum()
+	case typeArray:
+		return d.readArray()
+	case typeHash, typeHashDef:
+		return d.readHash(t == typeHashDef)
+	case typeObject:
+		return d.readObject()
+	case typeStruct:
+		return d.readStruct()
+	case typeUsrMarshal:
+		return d.readUserMarshal()
+	case typeClass, typeModule, typeModuleOld:
+		name, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		if t == typeClass {
+			return d.register(Class(name)), nil
+		}
+
+		return d.register(Module(name)), nil
+	}
+
+	return nil, UnsupportedTypeError
+}
+
+func (d *decoder) readString(ivars bool) (any, error) {
+	b, err := d.readBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := d.reserve()
+
+	var str any = append([]byte(nil), b...)
+	if ivars {
+		vars, err := d.readIvars()
+		if err != nil {
+			return nil, err
+		}
+
+		// Strings without an encoding are binary and stay []byte
+		_, utf8 := vars["E"]
+		_, named := vars["encoding"]
+		if utf8 || named {
+			str = string(b)
+		}
+	}
+
+	d.objects[idx] = str
+	return str, nil
+}
+
+func (d *decoder) readRegexp(ivars bool) (any, error) {
+	idx := d.reserve()
+
+	source, err := d.readBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	if ivars {
+		if _, err := d.readIvars(); err != nil {
+			return nil, err
+		}
+	}
+
+	re := &Regexp{Source: string(source), Options: options}
+	d.objects[idx] = re
+
+	return re, nil
+}
+
+func (d *decoder) readFloat() (any, error) {
+	b, err := d.readBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var f float64
+	switch s := string(b); s {
+	case "nan":
+		f = math.NaN()
+	case "inf":
+		f = math.Inf(1)
+	case "-inf":
+		f = math.Inf(-1)
+	default:
+		// Old Ruby versions append mantissa bits after a NUL byte
+		s, _, _ = strings.Cut(s, "\x00")
+
+		f, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, InvalidFormatError
+		}
+	}
+
+	return d.register(f), nil
+}
+
+func (d *decoder) readBignum() (any, error) {
+	sign, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := d.readLong()
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 || n*2 > len(d.data)-d.pos {
+		return nil, InvalidFormatError
+	}
+
+	// Little endian shorts
+	le := d.data[d.pos : d.pos+n*2]
+	d.pos += n * 2
+
+	be := make([]byte, len(le))
+	for i := range le {
+		be[len(le)-1-i] = le[i]
+	}
+
+	i := new(big.Int).SetBytes(be)
+	if sign == '-' {
+		i.Neg(i)
+	}
+
+	if i.IsInt64() {
+		return d.register(i.Int64()), nil
+	}
+
+	return d.register(i), nil
+}
+
+func (d *decoder) readArray() (any, error) {
+	n, err := d.readLong()
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, InvalidFormatError
+	}
+
+	idx := d.reserve()
+
+	arr := make([]any, n)
+	for i := range arr {
+		if arr[i], err = d.readValue(false); err != nil {
+			return nil, err
+		}
+	}
+
+	d.objects[idx] = arr
+	return arr, nil
+}
+
+func (d *decoder) readHash(withDefault bool) (any, error) {
+	n, err := d.readLong()
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, InvalidFormatError
+	}
+
+	idx := d.reserve()
+
+	h := &ruby.Hash{Pairs: make([]ruby.Pair, 0, n)}
+	for i := 0; i < n; i++ {
+		key, err := d.readValue(false)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := d.readValue(false)
+		if err != nil {
+			return nil, err
+		}
+
+		h.Set(key, value)
+	}
+
+	if withDefault {
+		if h.Default, err = d.readValue(false); err != nil {
+			return nil, err
+		}
+	}
+
+	d.objects[idx] = h
+	return h, nil
+}
+
+func (d *decoder) readObject() (any, error) {
+	class, err := d.readSymbol()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := d.reserve()
+
+	ivars, err := d.readIvars()
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &Object{Class: string(class), Ivars: ivars}
+	d.objects[idx] = obj
+
+	return obj, nil
+}
+
+func (d *decoder) readStruct() (any, error) {
+	class, err := d.readSymbol()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := d.reserve()
+
+	// Struct members are encoded like ivars, without the leading "@"
+	members, err := d.readIvars()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Struct{Class: string(class), Members: members}
+	d.objects[idx] = s
+
+	return s, nil
+}
+
+func (d *decoder) readUserDefined(ivars bool) (any, error) {
+	class, err := d.readSymbol()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := d.readBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Ruby reads the ivars of the dumped string before loading the object,
+	// so the object is registered after them
+	var vars map[string]any
+	if ivars {
+		if vars, err = d.readIvars(); err != nil {
+			return nil, err
+		}
+	}
+
+	ud := &UserDefined{Class: string(class), Data: append([]byte(nil), data...), Ivars: vars}
+
+	if ud.Class == "Time" {
+		t, err := loadTime(ud.Data, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		return d.register(t), nil
+	}
+
+	return d.register(ud), nil
+}
+
+func (d *decoder) readUserMarshal() (any, error) {
+	class, err := d.readSymbol()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := d.reserve()
+
+	data, err := d.readValue(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var v any = &UserMarshal{Class: string(class), Data: data}
+
+	// TimeWithZone dumps [utc, zone name, local time]
+	if class == "ActiveSupport::TimeWithZone" {
+		if parts, ok := data.([]any); ok && len(parts) == 3 {
+			if local, ok := parts[2].(time.Time); ok {
+				v = local
+			}
+		}
+	}
+
+	d.objects[idx] = v
+	return v, nil
+}
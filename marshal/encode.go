@@ -0,0 +1,498 @@
+package marshal
+
+import (
+	"cmp"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atitan/activesupport-go/internal/rubyconv"
+	"github.com/atitan/activesupport-go/ruby"
+)
+
+const (
+	fixnumMax = 1<<30 - 1
+	fixnumMin = -(1 << 30)
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// Marshal encodes plain data the way Marshal.dump does: nil, booleans,
+// integers, floats, strings, symbols, arrays, hashes and Time. Go strings are
+// written as UTF-8 Strings, []byte as binary Strings, and structs as Hashes
+// with String keys named after the marshal or json tag.
+func Marshal(v any) ([]byte, error) {
+	e := &encoder{
+		buf:     []byte{majorVersion, minorVersion},
+		symbols: make(map[ruby.Symbol]int),
+	}
+
+	if err := e.writeValue(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf     []byte
+	symbols map[ruby.Symbol]int
+}
+
+// writeLong writes the variable length integer encoding of w_long.
+func (e *encoder) writeLong(x int) {
+	switch {
+	case x == 0:
+		e.buf = append(e.buf, 0)
+	case 0 < x && x < 123:
+		e.buf = append(e.buf, byte(x+5))
+	case -124 < x && x < 0:
+		e.buf = append(e.buf, byte(int8(x-5)))
+	default:
+		var tmp [8]byte
+		n := 0
+		for n < len(tmp) {
+			tmp[n] = byte(x)
+			x >>= 8
+			n++
+
+			if x == 0 {
+				e.buf = append(e.buf, byte(n))
+				break
+			}
+			if x == -1 {
+				e.buf = append(e.buf, byte(int8(-n)))
+				break
+			}
+		}
+
+		e.buf = append(e.buf, tmp[:n]...)
+	}
+}
+
+func (e *encoder) writeBytes(b []byte) {
+	e.writeLong(len(b))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeSymbol(sym ruby.Symbol) {
+	if idx, ok := e.symbols[sym]; ok {
+		e.buf = append(e.buf, typeSymlink)
+		e.writeLong(idx)
+		return
+	}
+
+	e.symbols[sym] = len(e.symbols)
+	e.buf = append(e.buf, typeSymbol)
+	e.writeBytes([]byte(sym))
+}
+
+func (e *encoder) writeString(s string) {
+	e.buf = append(e.buf, typeIvar, typeString)
+	e.writeBytes([]byte(s))
+	e.writeLong(1)
+	e.writeSymbol("E")
+	e.buf = append(e.buf, typeTrue)
+}
+
+func (e *encoder) writeASCIIString(s string) {
+	e.buf = append(e.buf, typeIvar, typeString)
+	e.writeBytes([]byte(s))
+	e.writeLong(1)
+	e.writeSymbol("E")
+	e.buf = append(e.buf, typeFalse)
+}
+
+func (e *encoder) writeInt(i int64) {
+	if i < fixnumMin || i > fixnumMax {
+		e.writeBignum(big.NewInt(i))
+		return
+	}
+
+	e.buf = append(e.buf, typeFixnum)
+	e.writeLong(int(i))
+}
+
+func (e *encoder) writeBignum(i *big.Int) {
+	if i.IsInt64() && i.Int64() >= fixnumMin && i.Int64() <= fixnumMax {
+		e.writeInt(i.Int64())
+		return
+	}
+
+	sign := byte('+')
+	if i.Sign() < 0 {
+		sign = '-'
+	}
+
+	be := new(big.Int).Abs(i).Bytes()
+	if len(be)%2 != 0 {
+		be = append([]byte{0}, be...)
+	}
+
+	le := make([]byte, len(be))
+	for j := range be {
+		le[len(be)-1-j] = be[j]
+	}
+
+	e.buf = append(e.buf, typeBignum, sign)
+	e.writeLong(len(le) / 2)
+	e.buf = append(e.buf, le...)
+}
+
+func (e *encoder) writeFloat(f float64) {
+	e.buf = append(e.buf, typeFloat)
+	e.writeBytes([]byte(formatFloat(f)))
+}
+
+// formatFloat mirrors w_float, which writes the shortest representation of
+// the float that round trips.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	case f == 0:
+		if math.Signbit(f) {
+			return "-0"
+		}
+
+		return "0"
+	}
+
+	var b strings.Builder
+	if f < 0 {
+		b.WriteByte('-')
+		f = -f
+	}
+
+	// Shortest digits in the form d.ddde±x
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	x, _ := strconv.Atoi(exp)
+	decpt := x + 1
+
+	switch {
+	case decpt < -3 || decpt > len(digits):
+		b.WriteByte(digits[0])
+		if len(digits) > 1 {
+			b.WriteByte('.')
+			b.WriteString(digits[1:])
+		}
+		b.WriteString("e" + strconv.Itoa(decpt-1))
+	case decpt > 0:
+		b.WriteString(digits[:decpt])
+		if len(digits) > decpt {
+			b.WriteByte('.')
+			b.WriteString(digits[decpt:])
+		}
+	default:
+		b.WriteString("0.")
+		b.WriteString(strings.Repeat("0", -decpt))
+		b.WriteString(digits)
+	}
+
+	return b.String()
+}
+
+// writeTime mirrors time_mdump. Years outside the 16 bits of the dump give
+// YearOutOfRangeError, like "year too small/big to marshal".
+func (e *encoder) writeTime(t time.Time) error {
+	utc := t.Location() == time.UTC
+	u := t.UTC()
+
+	if u.Year() < 1900 {
+		return fmt.Errorf("%w: year too small to marshal: %d UTC", YearOutOfRangeError, u.Year())
+	}
+	if u.Year() > 1900+0xffff {
+		return fmt.Errorf("%w: year too big to marshal: %d UTC", YearOutOfRangeError, u.Year())
+	}
+
+	usec := u.Nanosecond() / 1000
+	nsec := u.Nanosecond() % 1000
+
+	p := uint32(1)<<31 |
+		boolBit(utc)<<30 |
+		uint32(u.Year()-1900)<<14 |
+		uint32(u.Month()-1)<<10 |
+		uint32(u.Day())<<5 |
+		uint32(u.Hour())
+	s := uint32(u.Minute())<<26 |
+		uint32(u.Second())<<20 |
+		uint32(usec)
+
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint32(data[0:4], p)
+	binary.LittleEndian.PutUint32(data[4:8], s)
+
+	zone, offset := t.Zone()
+
+	n := 0
+	if nsec != 0 {
+		n += 3
+	}
+	if !utc {
+		n++
+	}
+	if zone != "" {
+		n++
+	}
+
+	if n > 0 {
+		e.buf = append(e.buf, typeIvar)
+	}
+	e.buf = append(e.buf, typeUserDef)
+	e.writeSymbol("Time")
+	e.writeBytes(data)
+
+	if n == 0 {
+		return nil
+	}
+
+	e.writeLong(n)
+
+	if nsec != 0 {
+		e.writeSymbol("nano_num")
+		e.writeInt(int64(nsec))
+		e.writeSymbol("nano_den")
+		e.writeInt(1)
+
+		// Packed BCD of the nanoseconds, for Ruby 1.9.1 compatibility
+		submicro := []byte{byte(nsec/100)<<4 | byte(nsec/10%10), byte(nsec%10) << 4}
+		if submicro[1] == 0 {
+			submicro = submicro[:1]
+		}
+
+		e.writeSymbol("submicro")
+		e.buf = append(e.buf, typeString)
+		e.writeBytes(submicro)
+	}
+
+	if !utc {
+		e.writeSymbol("offset")
+		e.writeInt(int64(offset))
+	}
+
+	if zone != "" {
+		e.writeSymbol("zone")
+		e.writeASCIIString(zone)
+	}
+
+	return nil
+}
+
+func boolBit(b bool) uint32 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func (e *encoder) writeHash(h *ruby.Hash) error {
+	if h.Default != nil {
+		e.buf = append(e.buf, typeHashDef)
+	} else {
+		e.buf = append(e.buf, typeHash)
+	}
+
+	e.writeLong(len(h.Pairs))
+	for _, pair := range h.Pairs {
+		if err := e.writeValue(reflect.ValueOf(pair.Key)); err != nil {
+			return err
+		}
+
+		if err := e.writeValue(reflect.ValueOf(pair.Value)); err != nil {
+			return err
+		}
+	}
+
+	if h.Default != nil {
+		return e.writeValue(reflect.ValueOf(h.Default))
+	}
+
+	return nil
+}
+
+func (e *encoder) writeMap(v reflect.Value) error {
+	keys := v.MapKeys()
+	slices.SortFunc(keys, compareKeys)
+
+	e.buf = append(e.buf, typeHash)
+	e.writeLong(len(keys))
+	for _, key := range keys {
+		if err := e.writeValue(key); err != nil {
+			return err
+		}
+
+		if err := e.writeValue(v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compareKeys sorts map keys so the output is deterministic.
+func compareKeys(a, b reflect.Value) int {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+
+	if a.Kind() == b.Kind() {
+		switch a.Kind() {
+		case reflect.String:
+			return cmp.Compare(a.String(), b.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return cmp.Compare(a.Int(), b.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return cmp.Compare(a.Uint(), b.Uint())
+		}
+	}
+
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func (e *encoder) writeStruct(v reflect.Value) error {
+	fields := rubyconv.Fields(v.Type(), "marshal")
+
+	present := make([]rubyconv.Field, 0, len(fields))
+	for _, field := range fields {
+		if field.OmitEmpty && v.FieldByIndex(field.Index).IsZero() {
+			continue
+		}
+
+		present = append(present, field)
+	}
+
+	e.buf = append(e.buf, typeHash)
+	e.writeLong(len(present))
+	for _, field := range present {
+		e.writeString(field.Name)
+
+		if err := e.writeValue(v.FieldByIndex(field.Index)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *encoder) writeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, typeNil)
+		return nil
+	}
+
+	switch x := v.Interface().(type) {
+	case ruby.Symbol:
+		e.writeSymbol(x)
+		return nil
+	case ruby.ASCIIString:
+		e.writeASCIIString(string(x))
+		return nil
+	case *ruby.Hash:
+		if x == nil {
+			e.buf = append(e.buf, typeNil)
+			return nil
+		}
+
+		return e.writeHash(x)
+	case ruby.Hash:
+		return e.writeHash(&x)
+	case time.Time:
+		return e.writeTime(x)
+	case *big.Int:
+		if x == nil {
+			e.buf = append(e.buf, typeNil)
+			return nil
+		}
+
+		e.writeBignum(x)
+		return nil
+	case big.Int:
+		e.writeBignum(&x)
+		return nil
+	case []byte:
+		if x == nil {
+			e.buf = append(e.buf, typeNil)
+			return nil
+		}
+
+		e.buf = append(e.buf, typeString)
+		e.writeBytes(x)
+		return nil
+	}
+
+	if v.Type().Implements(textMarshalerType) && v.Kind() != reflect.Pointer {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+
+		e.writeString(string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			e.buf = append(e.buf, typeNil)
+			return nil
+		}
+
+		return e.writeValue(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, typeTrue)
+		} else {
+			e.buf = append(e.buf, typeFalse)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeBignum(new(big.Int).SetUint64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		e.writeFloat(v.Float())
+	case reflect.String:
+		e.writeString(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			e.buf = append(e.buf, typeNil)
+			return nil
+		}
+
+		e.buf = append(e.buf, typeArray)
+		e.writeLong(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := e.writeValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, typeNil)
+			return nil
+		}
+
+		return e.writeMap(v)
+	case reflect.Struct:
+		return e.writeStruct(v)
+	default:
+		return fmt.Errorf("%w: %s", UnsupportedTypeError, v.Type())
+	}
+
+	return nil
+}
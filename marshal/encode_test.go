@@ -0,0 +1,152 @@
+package marshal
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/ruby"
+)
+
+func TestMarshalString(t *testing.T) {
+	out, err := Marshal("signed message")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if expected := []byte("\x04\bI\"\x13signed message\x06:\x06ET"); !bytes.Equal(out, expected) {
+		t.Errorf("data mismatch: %q, %q", out, expected)
+	}
+}
+
+func TestMarshalFixnum(t *testing.T) {
+	fixnums := map[int64]string{
+		0:          "\x04\bi\x00",
+		1:          "\x04\bi\x06",
+		122:        "\x04\bi\x7f",
+		123:        "\x04\bi\x01{",
+		256:        "\x04\bi\x02\x00\x01",
+		1073741823: "\x04\bi\x04\xff\xff\xff?",
+		-1:         "\x04\bi\xfa",
+		-123:       "\x04\bi\x80",
+		-124:       "\x04\bi\xff\x84",
+		-256:       "\x04\bi\xff\x00",
+		1 << 31:    "\x04\bl+\a\x00\x00\x00\x80",
+	}
+
+	for src, dst := range fixnums {
+		out, err := Marshal(src)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(out) != dst {
+			t.Errorf("input: %d; want %q; got: %q", src, dst, out)
+		}
+	}
+}
+
+func TestMarshalBignum(t *testing.T) {
+	out, err := Marshal(new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if expected := []byte("\x04\bl+\n\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00"); !bytes.Equal(out, expected) {
+		t.Errorf("data mismatch: %q, %q", out, expected)
+	}
+}
+
+func TestMarshalFloat(t *testing.T) {
+	floats := map[float64]string{
+		1.5:          "1.5",
+		100:          "1e2",
+		123.456:      "123.456",
+		0.0001:       "0.0001",
+		0.00001:      "1e-5",
+		-2.5e20:      "-2.5e20",
+		0:            "0",
+		math.Inf(-1): "-inf",
+	}
+
+	for src, dst := range floats {
+		if out := formatFloat(src); out != dst {
+			t.Errorf("input: %v; want %q; got: %q", src, dst, out)
+		}
+	}
+}
+
+func TestMarshalComplexStruct(t *testing.T) {
+	out, err := Marshal(Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := []byte("\x04\b{\tI\"\aab\x06:\x06ETi\x01{I\"\acd\x06;\x00TI\"\vyellow\x06;\x00TI\"\aef\x06;\x00TTI\"\agh\x06;\x00T0")
+	if !bytes.Equal(out, expected) {
+		t.Errorf("data mismatch: %q, %q", out, expected)
+	}
+}
+
+func TestMarshalSortedMap(t *testing.T) {
+	out, err := Marshal(map[ruby.Symbol]any{"b": []any{nil, false}, "a": 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if expected := []byte("\x04\b{\a:\x06ai\x06:\x06b[\a0F"); !bytes.Equal(out, expected) {
+		t.Errorf("data mismatch: %q, %q", out, expected)
+	}
+}
+
+func TestMarshalTimeUTC(t *testing.T) {
+	out, err := Marshal(time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	p := uint32(1<<31 | 1<<30 | 107<<14 | 0<<10 | 1<<5 | 0)
+	expected := []byte("\x04\bIu:\tTime\r" + dumpedTime(p, 0) + "\x06:\tzoneI\"\bUTC\x06:\x06EF")
+	if !bytes.Equal(out, expected) {
+		t.Errorf("data mismatch: %q, %q", out, expected)
+	}
+}
+
+func TestMarshalTimeYearOutOfRange(t *testing.T) {
+	for _, year := range []int{1899, 1900 + 0x10000} {
+		if _, err := Marshal(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, YearOutOfRangeError) {
+			t.Errorf("year: %d; expected YearOutOfRangeError, got: %v", year, err)
+		}
+	}
+
+	if _, err := Marshal(time.Date(1900+0xffff, 12, 31, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMarshalTimeRoundTrip(t *testing.T) {
+	original := time.Date(2007, 1, 1, 8, 0, 0, 123456789, time.FixedZone("", 28800))
+
+	out, err := Marshal(original)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var data time.Time
+	if err := Unmarshal(out, &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if !data.Equal(original) {
+		t.Errorf("data mismatch: %v, %v", data, original)
+	}
+	if _, offset := data.Zone(); offset != 28800 {
+		t.Errorf("offset mismatch: %d", offset)
+	}
+}
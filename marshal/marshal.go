@@ -0,0 +1,90 @@
+// Package marshal reads data produced by Ruby's Marshal.dump (format 4.8).
+package marshal
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/atitan/activesupport-go/internal/rubyconv"
+)
+
+const (
+	majorVersion = 4
+	minorVersion = 8
+)
+
+var (
+	InvalidFormatError   = errors.New("marshal: invalid format")
+	UnsupportedTypeError = errors.New("marshal: unsupported type")
+	RecursiveLinkError   = errors.New("marshal: recursive structure unsupported")
+	YearOutOfRangeError  = errors.New("marshal: year out of range")
+)
+
+// Object is an instance of a plain Ruby class, with its instance variables
+// keyed by name including the leading "@".
+type Object struct {
+	Class string
+	Ivars map[string]any
+}
+
+// UserDefined is an instance of a class implementing _dump and _load.
+type UserDefined struct {
+	Class string
+	Data  []byte
+	Ivars map[string]any
+}
+
+// UserMarshal is an instance of a class implementing marshal_dump and
+// marshal_load, with Data being the result of marshal_dump.
+type UserMarshal struct {
+	Class string
+	Data  any
+}
+
+type Struct struct {
+	Class   string
+	Members map[string]any
+}
+
+type Regexp struct {
+	Source  string
+	Options byte
+}
+
+type Class string
+
+type Module string
+
+// Unmarshal decodes a Marshal payload into v following the rules of
+// encoding/json. Hashes are decoded into structs by matching their String or
+// Symbol keys against the marshal tag, then the json tag, then field names.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("marshal: Unmarshal(%T)", v)
+	}
+
+	value, err := Load(data)
+	if err != nil {
+		return err
+	}
+
+	if err := rubyconv.Assign(rv.Elem(), value, "marshal"); err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	return nil
+}
+
+// Load decodes a Marshal payload into its raw Ruby representation, with
+// hashes kept as ordered *ruby.Hash.
+func Load(data []byte) (any, error) {
+	if len(data) < 2 || data[0] != majorVersion || data[1] > minorVersion {
+		return nil, InvalidFormatError
+	}
+
+	d := &decoder{data: data, pos: 2}
+
+	return d.readValue(false)
+}
@@ -0,0 +1,270 @@
+package marshal
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/ruby"
+	"github.com/google/go-cmp/cmp"
+)
+
+type Complex struct {
+	Ab int    `json:"ab"`
+	Cd string `json:"cd"`
+	Ef bool   `json:"ef"`
+	Gh *int   `json:"gh"`
+}
+
+func dumpedTime(p, s uint32) string {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b[0:4], p)
+	binary.LittleEndian.PutUint32(b[4:8], s)
+
+	return string(b)
+}
+
+func TestUnmarshalString(t *testing.T) {
+	var data string
+	if err := Unmarshal([]byte("\x04\bI\"\x13signed message\x06:\x06ET"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "signed message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+}
+
+func TestUnmarshalBinaryString(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\x04\b\"\a\x00\xff"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff([]byte{0x00, 0xff}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalFixnum(t *testing.T) {
+	fixnums := map[string]int64{
+		"\x04\bi\x00":              0,
+		"\x04\bi\x06":              1,
+		"\x04\bi\x7f":              122,
+		"\x04\bi\x01{":             123,
+		"\x04\bi\x02\x00\x01":      256,
+		"\x04\bi\x04\xff\xff\xff?": 1073741823,
+		"\x04\bi\xfa":              -1,
+		"\x04\bi\x80":              -123,
+		"\x04\bi\xff\x84":          -124,
+		"\x04\bi\xff\x00":          -256,
+	}
+
+	for src, want := range fixnums {
+		var data int64
+		if err := Unmarshal([]byte(src), &data); err != nil {
+			t.Errorf("input: %q; %v", src, err)
+			continue
+		}
+		if data != want {
+			t.Errorf("input: %q; want %d; got: %d", src, want, data)
+		}
+	}
+}
+
+func TestUnmarshalBignum(t *testing.T) {
+	var small int64
+	if err := Unmarshal([]byte("\x04\bl+\a\x00\x00\x00\x80"), &small); err != nil {
+		t.Error(err)
+		return
+	}
+	if small != 1<<31 {
+		t.Errorf("data mismatch: %d", small)
+	}
+
+	var large *big.Int
+	if err := Unmarshal([]byte("\x04\bl+\n\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00"), &large); err != nil {
+		t.Error(err)
+		return
+	}
+	if want := new(big.Int).Lsh(big.NewInt(1), 64); large.Cmp(want) != 0 {
+		t.Errorf("data mismatch: %s", large)
+	}
+}
+
+func TestUnmarshalFloat(t *testing.T) {
+	var data float64
+	if err := Unmarshal([]byte("\x04\bf\b1.5"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != 1.5 {
+		t.Errorf("data mismatch: %v", data)
+	}
+}
+
+func TestUnmarshalSymbol(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\x04\b:\bfoo"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != ruby.Symbol("foo") {
+		t.Errorf("data mismatch: %#v", data)
+	}
+}
+
+func TestUnmarshalLinks(t *testing.T) {
+	var data []string
+	if err := Unmarshal([]byte("\x04\b[\bI\"\x06a\x06:\x06ETI\"\x06b\x06;\x00T@\x06"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff([]string{"a", "b", "a"}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalRecursiveLink(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\x04\b[\x06@\x00"), &data); !errors.Is(err, RecursiveLinkError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
+
+func TestUnmarshalComplexHash(t *testing.T) {
+	src := "\x04\b{\tI\"\aab\x06:\x06ETi\x01{I\"\acd\x06;\x00TI\"\vyellow\x06;\x00TI\"\aef\x06;\x00TTI\"\agh\x06;\x00T0"
+
+	var data Complex
+	if err := Unmarshal([]byte(src), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalHashWithIndifferentAccess(t *testing.T) {
+	class := "ActiveSupport::HashWithIndifferentAccess"
+	src := "\x04\bC:" + string(rune(len(class)+5)) + class + "{\x06I\"\x06a\x06:\x06ETi\x06"
+
+	var data any
+	if err := Unmarshal([]byte(src), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(map[string]any{"a": int64(1)}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalIvarsOnObjects(t *testing.T) {
+	// a = [1]; a.instance_variable_set(:@x, 2); h = {}; h.instance_variable_set(:@y, 3)
+	// Marshal.dump([a, h, "s"])
+	src := "\x04\b[\bI[\x06i\x06\x06:\a@xi\aI{\x00\x06:\a@yi\bI\"\x06s\x06:\x06ET"
+
+	var data any
+	if err := Unmarshal([]byte(src), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	want := []any{[]any{int64(1)}, map[string]any{}, "s"}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalIvarsTooLong(t *testing.T) {
+	for _, src := range []string{
+		"\x04\bI[\x00\x04\xff\xff\xff\x3f",
+		"\x04\bo:\bFoo\x04\xff\xff\xff\x3f",
+		"\x04\bS:\bFoo\x04\xff\xff\xff\x3f",
+	} {
+		var data any
+		if err := Unmarshal([]byte(src), &data); !errors.Is(err, InvalidFormatError) {
+			t.Errorf("input: %q; unexpected err: %v", src, err)
+		}
+	}
+}
+
+func TestUnmarshalHashWithDefault(t *testing.T) {
+	var data ruby.Hash
+	if err := Unmarshal([]byte("\x04\b}\x06I\"\x06a\x06:\x06ETi\x06i\n"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	want := ruby.Hash{Pairs: []ruby.Pair{{Key: "a", Value: int64(1)}}, Default: int64(5)}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalTimeUTC(t *testing.T) {
+	p := uint32(1<<31 | 1<<30 | 107<<14 | 0<<10 | 1<<5 | 0)
+	src := "\x04\bIu:\tTime\r" + dumpedTime(p, 0) + "\x06:\tzoneI\"\bUTC\x06:\x06EF"
+
+	var data time.Time
+	if err := Unmarshal([]byte(src), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if want := time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC); !data.Equal(want) || data.Location() != time.UTC {
+		t.Errorf("data mismatch: %v", data)
+	}
+}
+
+func TestUnmarshalTimeOffsetNano(t *testing.T) {
+	p := uint32(1<<31 | 0<<30 | 107<<14 | 0<<10 | 1<<5 | 0)
+	s := uint32(0<<26 | 0<<20 | 123456)
+	src := "\x04\bIu:\tTime\r" + dumpedTime(p, s) + "\t" +
+		":\rnano_numi\x02\x15\x03" +
+		":\rnano_deni\x06" +
+		":\rsubmicro\"\ax\x90" +
+		":\voffseti\x02\x80p"
+
+	var data time.Time
+	if err := Unmarshal([]byte(src), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if want := time.Date(2007, 1, 1, 0, 0, 0, 123456789, time.UTC); !data.Equal(want) {
+		t.Errorf("data mismatch: %v", data)
+	}
+	if _, offset := data.Zone(); offset != 28800 {
+		t.Errorf("offset mismatch: %d", offset)
+	}
+}
+
+func TestUnmarshalObject(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\x04\bo:\bFoo\x06:\a@ai\x06"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	want := &Object{Class: "Foo", Ivars: map[string]any{"@a": int64(1)}}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalUserDefined(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\x04\bu:\x0fBigDecimal\x0e18:0.15e1"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	want := &UserDefined{Class: "BigDecimal", Data: []byte("18:0.15e1")}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalInvalidVersion(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\x04\x09i\x06"), &data); !errors.Is(err, InvalidFormatError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
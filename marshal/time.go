@@ -0,0 +1,86 @@
+package marshal
+
+import (
+	"encoding/binary"
+	"math/big"
+	"time"
+)
+
+// loadTime mirrors time_mload of Ruby, reading the 8 bytes produced by
+// Time#_dump along with its offset, zone and nano ivars.
+func loadTime(data []byte, ivars map[string]any) (time.Time, error) {
+	if len(data) != 8 {
+		return time.Time{}, InvalidFormatError
+	}
+
+	p := binary.LittleEndian.Uint32(data[0:4])
+	s := binary.LittleEndian.Uint32(data[4:8])
+
+	var t time.Time
+	utc := false
+
+	if p&(1<<31) == 0 {
+		// Format used before Ruby 1.9, p is seconds and s is microseconds
+		t = time.Unix(int64(p), int64(s)*1000).UTC()
+	} else {
+		utc = (p>>30)&1 == 1
+		year := int((p>>14)&0xffff) + 1900
+		month := time.Month((p>>10)&0xf + 1)
+		day := int((p >> 5) & 0x1f)
+		hour := int(p & 0x1f)
+		min := int((s >> 26) & 0x3f)
+		sec := int((s >> 20) & 0x3f)
+		usec := int(s & 0xfffff)
+
+		t = time.Date(year, month, day, hour, min, sec, usec*1000, time.UTC)
+	}
+
+	num, okNum := ivars["nano_num"]
+	den, okDen := ivars["nano_den"]
+	if okNum && okDen {
+		nano, ok := ratOf(num, den)
+		if !ok {
+			return time.Time{}, InvalidFormatError
+		}
+
+		ns, _ := nano.Float64()
+		t = t.Add(time.Duration(ns))
+	}
+
+	if utc {
+		return t, nil
+	}
+
+	offset, ok := ivars["offset"].(int64)
+	if !ok {
+		return t.Local(), nil
+	}
+
+	zone, _ := ivars["zone"].(string)
+	return t.In(time.FixedZone(zone, int(offset))), nil
+}
+
+func ratOf(num, den any) (*big.Rat, bool) {
+	n, ok := bigOf(num)
+	if !ok {
+		return nil, false
+	}
+
+	d, ok := bigOf(den)
+	if !ok || d.Sign() == 0 {
+		return nil, false
+	}
+
+	return new(big.Rat).SetFrac(n, d), true
+}
+
+func bigOf(v any) (*big.Int, bool) {
+	switch i := v.(type) {
+	case int64:
+		return big.NewInt(i), true
+	case *big.Int:
+		return i, true
+	}
+
+	return nil, false
+}
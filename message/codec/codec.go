@@ -1,11 +1,14 @@
 package codec
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/atitan/activesupport-go/ruby"
 )
 
 var (
@@ -15,8 +18,13 @@ var (
 	ExpiredError           = errors.New("codec: data expired")
 	MismatchedPurposeError = errors.New("codec: mismatched purpose")
 	InvalidMetadataError   = errors.New("codec: invalid metadata")
+
+	legacyEnvelopePrefix = []byte(`{"_rails":{"message":`)
 )
 
+// expiryFormat matches Time#iso8601(3) of a UTC time.
+const expiryFormat = "2006-01-02T15:04:05.000Z07:00"
+
 type Envelope struct {
 	Rails Metadata `json:"_rails"`
 }
@@ -28,42 +36,135 @@ type Metadata struct {
 	Purpose string          `json:"pur,omitempty"`
 }
 
+// envelopeHeader is decoded ahead of the data to tell whether a payload is
+// wrapped in a metadata envelope.
+type envelopeHeader struct {
+	Rails *struct {
+		Expiry  *time.Time `json:"exp"`
+		Purpose string     `json:"pur"`
+	} `json:"_rails"`
+}
+
+// dataEnvelope decodes the data of a modern envelope straight into a value,
+// with any serializer.
+type dataEnvelope struct {
+	Rails struct {
+		Data any `json:"data"`
+	} `json:"_rails"`
+}
+
+// metadataEnvelope and legacyMetadataEnvelope are written key for key like
+// the hashes built by Rails, so generated messages match byte for byte.
+type metadataEnvelope struct {
+	Rails struct {
+		Data    any              `json:"data"`
+		Expiry  ruby.ASCIIString `json:"exp,omitempty"`
+		Purpose string           `json:"pur,omitempty"`
+	} `json:"_rails"`
+}
+
+type legacyMetadataEnvelope struct {
+	Rails struct {
+		Message string  `json:"message"`
+		Expiry  *string `json:"exp"`
+		Purpose *string `json:"pur"`
+	} `json:"_rails"`
+}
+
 type MetadataOption struct {
 	ExpiresAt *time.Time
 	ExpiresIn *time.Duration
 	Purpose   string
 }
 
-func (mo MetadataOption) pickExpiry() *time.Time {
-	if mo.ExpiresAt != nil {
-		return mo.ExpiresAt
-	}
+func (mo MetadataOption) isEmpty() bool {
+	return mo.ExpiresAt == nil && mo.ExpiresIn == nil && mo.Purpose == ""
+}
 
-	if mo.ExpiresIn != nil {
-		t := time.Now().Add(*mo.ExpiresIn)
-		return &t
+// pickExpiry formats the expiry like Time#iso8601(3) in UTC, or returns an
+// empty string without one.
+func (mo MetadataOption) pickExpiry(now time.Time) string {
+	var expiry time.Time
+
+	switch {
+	case mo.ExpiresAt != nil:
+		expiry = *mo.ExpiresAt
+	case mo.ExpiresIn != nil:
+		expiry = now.Add(*mo.ExpiresIn)
+	default:
+		return ""
 	}
 
-	return nil
+	return expiry.UTC().Format(expiryFormat)
 }
 
 type Codec struct {
 	urlSafe        bool
+	paddedURLSafe  bool
 	legacyMetadata bool
+	serializer     Serializer
+	now            func() time.Time
 }
 
 func New(urlSafe, legacyMetadata bool) Codec {
 	return Codec{
 		urlSafe:        urlSafe,
 		legacyMetadata: legacyMetadata,
+		serializer:     JSON,
+		now:            time.Now,
 	}
 }
 
+// WithSerializer returns a copy of the codec which serializes messages with s
+// instead of JSON.
+func (c Codec) WithSerializer(s Serializer) Codec {
+	c.serializer = s
+
+	return c
+}
+
+// WithClock returns a copy of the codec which reads the current time from now
+// instead of time.Now, both to compute and to check expiries.
+func (c Codec) WithClock(now func() time.Time) Codec {
+	c.now = now
+
+	return c
+}
+
+// WithPaddedURLSafe returns a copy of the codec which encodes with padded
+// URL-safe base64, like Base64.urlsafe_encode64, and decodes URL-safe base64
+// with or without padding, like Base64.urlsafe_decode64. GlobalID::Verifier
+// encodes messages this way.
+func (c Codec) WithPaddedURLSafe() Codec {
+	c.paddedURLSafe = true
+
+	return c
+}
+
+func (c Codec) currentTime() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+
+	return c.now()
+}
+
 func (c Codec) Encode(src []byte) []byte {
+	if c.paddedURLSafe {
+		dst := make([]byte, base64.URLEncoding.EncodedLen(len(src)))
+		base64.URLEncoding.Encode(dst, src)
+
+		return dst
+	}
+
 	return Encode(src, c.urlSafe)
 }
 
 func (c Codec) Decode(src []byte) ([]byte, error) {
+	if c.paddedURLSafe {
+		return Decode(bytes.TrimRight(src, "="), true)
+	}
+
 	decoded, err := Decode(src, c.urlSafe)
 	if err == nil {
 		return decoded, nil
@@ -75,85 +176,103 @@ func (c Codec) Decode(src []byte) ([]byte, error) {
 }
 
 func (c Codec) SerializeWithMetadata(data any, opt MetadataOption) ([]byte, error) {
+	// Like Rails, messages without metadata are not wrapped in an envelope
+	if opt.isEmpty() {
+		return c.serializer.Marshal(data)
+	}
+
+	expiry := opt.pickExpiry(c.currentTime())
+
 	if c.legacyMetadata {
-		serialized, err := json.Marshal(data)
+		serialized, err := c.serializer.Marshal(data)
 		if err != nil {
 			return nil, err
 		}
 
-		msg := Encode(serialized, false)
-		env := Envelope{
-			Rails: Metadata{
-				Message: string(msg),
-				Expiry:  opt.pickExpiry(),
-				Purpose: opt.Purpose,
-			},
+		// Legacy envelope is always JSON, wrapping the serialized message
+		var env legacyMetadataEnvelope
+		env.Rails.Message = string(Encode(serialized, false))
+		if expiry != "" {
+			env.Rails.Expiry = &expiry
+		}
+		if opt.Purpose != "" {
+			env.Rails.Purpose = &opt.Purpose
 		}
 
 		return json.Marshal(env)
 	} else {
-		serialized, err := json.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
+		var env metadataEnvelope
+		env.Rails.Data = data
+		env.Rails.Expiry = ruby.ASCIIString(expiry)
+		env.Rails.Purpose = opt.Purpose
 
-		env := Envelope{
-			Rails: Metadata{
-				Data:    serialized,
-				Expiry:  opt.pickExpiry(),
-				Purpose: opt.Purpose,
-			},
-		}
-
-		return json.Marshal(env)
+		return c.serializer.Marshal(env)
 	}
 }
 
 func (c Codec) DeserializeWithMetadata(data []byte, v any, opt MetadataOption) error {
-	var env Envelope
-	if err := json.Unmarshal(data, &env); err != nil {
-		// The data is not an envelope, try unmarshal it directly
-		if err := json.Unmarshal(data, v); err != nil {
-			return fmt.Errorf("unmarshal directly: %w", err)
+	// Legacy metadata
+	if bytes.HasPrefix(data, legacyEnvelopePrefix) {
+		var env Envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			return InvalidMetadataError
 		}
 
-		return nil
-	}
-
-	meta := env.Rails
-
-	if meta.Expiry != nil && time.Now().After(*meta.Expiry) {
-		return ExpiredError
-	}
-
-	if meta.Purpose != opt.Purpose {
-		return MismatchedPurposeError
-	}
+		if err := c.verifyMetadata(env.Rails.Expiry, env.Rails.Purpose, opt); err != nil {
+			return err
+		}
 
-	// Legacy metadata
-	if meta.Message != "" {
-		serialized, err := Decode([]byte(meta.Message), false)
+		serialized, err := Decode([]byte(env.Rails.Message), false)
 		if err != nil {
 			return InvalidMetadataError
 		}
 
-		if err := json.Unmarshal(serialized, v); err != nil {
+		if err := c.serializer.Unmarshal(serialized, v); err != nil {
 			return fmt.Errorf("unmarshal legacy metadata: %w", err)
 		}
 
 		return nil
 	}
 
-	// Modern metadata
-	if meta.Data != nil {
-		if err := json.Unmarshal(meta.Data, v); err != nil {
-			return fmt.Errorf("unmarshal modern metadata: %w", err)
+	var header envelopeHeader
+	if err := c.serializer.Unmarshal(data, &header); err != nil || header.Rails == nil {
+		// The data is not an envelope, which is only valid without expected metadata
+		if opt.Purpose != "" {
+			return MismatchedPurposeError
+		}
+
+		if err := c.serializer.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("unmarshal directly: %w", err)
 		}
 
 		return nil
 	}
 
-	return InvalidMetadataError
+	if err := c.verifyMetadata(header.Rails.Expiry, header.Rails.Purpose, opt); err != nil {
+		return err
+	}
+
+	// Modern metadata, decode the data straight into v
+	var env dataEnvelope
+	env.Rails.Data = v
+	if err := c.serializer.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("unmarshal modern metadata: %w", err)
+	}
+
+	return nil
+}
+
+func (c Codec) verifyMetadata(expiry *time.Time, purpose string, opt MetadataOption) error {
+	// Rails treats a message as expired from the very moment of its expiry
+	if expiry != nil && !c.currentTime().Before(*expiry) {
+		return ExpiredError
+	}
+
+	if purpose != opt.Purpose {
+		return MismatchedPurposeError
+	}
+
+	return nil
 }
 
 func Encode(src []byte, urlSafe bool) []byte {
@@ -1,7 +1,11 @@
 package codec
 
 import (
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestEncode(t *testing.T) {
@@ -43,3 +47,92 @@ func TestEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestPaddedURLSafe(t *testing.T) {
+	c := New(false, false).WithPaddedURLSafe()
+
+	for src, dst := range map[string]string{
+		"1":   "MQ==",
+		"ÿÿÿ": "w7_Dv8O_",
+		">?":  "Pj8=",
+	} {
+		if out := string(c.Encode([]byte(src))); out != dst {
+			t.Errorf("input: %s; want %s; got: %s", src, dst, out)
+		}
+
+		for _, encoded := range []string{dst, strings.TrimRight(dst, "=")} {
+			if out, err := c.Decode([]byte(encoded)); err != nil || string(out) != src {
+				t.Errorf("input: %s; want %s; got: %s, %v", encoded, src, out, err)
+			}
+		}
+	}
+
+	if _, err := c.Decode([]byte("w7/Dv8O/")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSerializeExpiry(t *testing.T) {
+	now := time.Date(2007, 1, 1, 8, 0, 0, 123456789, time.FixedZone("", 8*3600))
+	c := New(false, false).WithClock(func() time.Time { return now })
+
+	expiresIn := time.Hour
+	serialized, err := c.SerializeWithMetadata("signed message", MetadataOption{ExpiresIn: &expiresIn})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if want := `{"_rails":{"data":"signed message","exp":"2007-01-01T01:00:00.123Z"}}`; string(serialized) != want {
+		t.Errorf("serialized mismatch: %s", serialized)
+	}
+
+	serialized, err = New(false, true).WithClock(func() time.Time { return now }).SerializeWithMetadata("signed message", MetadataOption{Purpose: "pizza"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if want := `{"_rails":{"message":"InNpZ25lZCBtZXNzYWdlIg==","exp":null,"pur":"pizza"}}`; string(serialized) != want {
+		t.Errorf("serialized mismatch: %s", serialized)
+	}
+
+	serialized, err = c.SerializeWithMetadata("signed message", MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if want := `"signed message"`; string(serialized) != want {
+		t.Errorf("serialized mismatch: %s", serialized)
+	}
+}
+
+func TestDeserializeExpiry(t *testing.T) {
+	now := time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New(false, false).WithClock(func() time.Time { return now })
+
+	serialized := []byte(`{"_rails":{"data":"signed message","exp":"2007-01-01T00:00:00.001Z"}}`)
+
+	var data string
+	if err := c.DeserializeWithMetadata(serialized, &data, MetadataOption{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "signed message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+
+	now = now.Add(time.Millisecond)
+	if err := c.DeserializeWithMetadata(serialized, &data, MetadataOption{}); !errors.Is(err, ExpiredError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
+
+func TestEnvelopeData(t *testing.T) {
+	var env Envelope
+	if err := json.Unmarshal([]byte(`{"_rails":{"data":{"a":1},"pur":"pizza"}}`), &env); err != nil {
+		t.Error(err)
+		return
+	}
+	if string(env.Rails.Data) != `{"a":1}` || env.Rails.Purpose != "pizza" {
+		t.Errorf("unexpected envelope: %+v", env)
+	}
+}
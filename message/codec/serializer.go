@@ -0,0 +1,173 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/atitan/activesupport-go/marshal"
+	"github.com/atitan/activesupport-go/messagepack"
+)
+
+type Serializer interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+type Format string
+
+const (
+	FormatMarshal     Format = "marshal"
+	FormatJSON        Format = "json"
+	FormatMessagePack Format = "message_pack"
+)
+
+var (
+	UnsupportedFormatError = errors.New("codec: unsupported serialization format")
+
+	JSON        Serializer = jsonSerializer{}
+	Marshal     Serializer = marshalSerializer{}
+	MessagePack Serializer = messagePackSerializer{}
+
+	marshalSignature     = []byte{0x04, 0x08}
+	messagePackSignature = []byte{0xcc, 0x80}
+)
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonSerializer) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type marshalSerializer struct{}
+
+func (marshalSerializer) Marshal(v any) ([]byte, error) {
+	return marshal.Marshal(v)
+}
+
+func (marshalSerializer) Unmarshal(data []byte, v any) error {
+	return marshal.Unmarshal(data, v)
+}
+
+type messagePackSerializer struct{}
+
+func (messagePackSerializer) Marshal(v any) ([]byte, error) {
+	return messagepack.Marshal(v)
+}
+
+func (messagePackSerializer) Unmarshal(data []byte, v any) error {
+	return messagepack.Unmarshal(data, v)
+}
+
+// SerializerFor returns the plain serializer of a format, without fallback.
+func SerializerFor(format Format) (Serializer, error) {
+	switch format {
+	case FormatJSON:
+		return JSON, nil
+	case FormatMarshal:
+		return Marshal, nil
+	case FormatMessagePack:
+		return MessagePack, nil
+	}
+
+	return nil, UnsupportedFormatError
+}
+
+// SerializerWithFallback mirrors ActiveSupport::Messages::SerializerWithFallback.
+// The returned serializer dumps with the named format, and loads any payload
+// whose format can be detected, except Marshal unless explicitly allowed.
+//
+// Supported names are marshal, json, json_allow_marshal, message_pack and
+// message_pack_allow_marshal.
+func SerializerWithFallback(name string) (Serializer, error) {
+	var s fallbackSerializer
+
+	switch name {
+	case "marshal":
+		s = fallbackSerializer{format: FormatMarshal, allowMarshal: true}
+	case "json":
+		s = fallbackSerializer{format: FormatJSON}
+	case "json_allow_marshal":
+		s = fallbackSerializer{format: FormatJSON, allowMarshal: true}
+	case "message_pack":
+		s = fallbackSerializer{format: FormatMessagePack}
+	case "message_pack_allow_marshal":
+		s = fallbackSerializer{format: FormatMessagePack, allowMarshal: true}
+	default:
+		return nil, UnsupportedFormatError
+	}
+
+	if _, err := SerializerFor(s.format); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+type fallbackSerializer struct {
+	format       Format
+	allowMarshal bool
+}
+
+func (s fallbackSerializer) Marshal(v any) ([]byte, error) {
+	serializer, err := SerializerFor(s.format)
+	if err != nil {
+		return nil, err
+	}
+
+	return serializer.Marshal(v)
+}
+
+func (s fallbackSerializer) Unmarshal(data []byte, v any) error {
+	format := DetectFormat(data)
+	if format == "" || (format == FormatMarshal && format != s.format && !s.allowMarshal) {
+		return UnsupportedFormatError
+	}
+
+	serializer, err := SerializerFor(format)
+	if err != nil {
+		return err
+	}
+
+	return serializer.Unmarshal(data, v)
+}
+
+// DetectFormat sniffs the leading bytes of a payload the same way Rails does
+// to pick a fallback serializer. It returns an empty format if unknown.
+func DetectFormat(data []byte) Format {
+	switch {
+	case bytes.HasPrefix(data, messagePackSignature):
+		return FormatMessagePack
+	case bytes.HasPrefix(data, marshalSignature):
+		return FormatMarshal
+	case isJSON(data):
+		return FormatJSON
+	}
+
+	return ""
+}
+
+func isJSON(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	switch c := data[0]; {
+	case c == '{', c == '[', c == '"', isDigit(c):
+		return true
+	case c == '-':
+		return len(data) > 1 && isDigit(data[1])
+	}
+
+	return bytes.HasPrefix(data, []byte("true")) ||
+		bytes.HasPrefix(data, []byte("false")) ||
+		bytes.HasPrefix(data, []byte("null"))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,183 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type prefixedSerializer struct{}
+
+func (prefixedSerializer) Marshal(v any) ([]byte, error) {
+	serialized, err := JSON.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte("~"), serialized...), nil
+}
+
+func (prefixedSerializer) Unmarshal(data []byte, v any) error {
+	data, found := bytes.CutPrefix(data, []byte("~"))
+	if !found {
+		return UnsupportedFormatError
+	}
+
+	return JSON.Unmarshal(data, v)
+}
+
+func TestDetectFormat(t *testing.T) {
+	formats := map[string]Format{
+		"\x04\bI\"\x13signed message\x06:\x06ET": FormatMarshal,
+		"\xcc\x80\xaesigned message":             FormatMessagePack,
+		`{"_rails":{"data":1}}`:                  FormatJSON,
+		`["a"]`:                                  FormatJSON,
+		`"signed message"`:                       FormatJSON,
+		`-12`:                                    FormatJSON,
+		`null`:                                   FormatJSON,
+		"signed message":                         "",
+		"-signed message":                        "",
+		"-":                                      "",
+		"":                                       "",
+	}
+
+	for src, format := range formats {
+		if out := DetectFormat([]byte(src)); out != format {
+			t.Errorf("input: %q; want %q; got: %q", src, format, out)
+		}
+	}
+}
+
+func TestSerializerWithFallback(t *testing.T) {
+	s, err := SerializerWithFallback("json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var data string
+	if err := s.Unmarshal([]byte(`"signed message"`), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "signed message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+
+	if err := s.Unmarshal([]byte("\x04\bI\"\x13signed message\x06:\x06ET"), &data); !errors.Is(err, UnsupportedFormatError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+
+	if _, err := SerializerWithFallback("yaml"); !errors.Is(err, UnsupportedFormatError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
+
+func TestSerializeWithCustomSerializer(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		c := New(false, legacy).WithSerializer(prefixedSerializer{})
+		opt := MetadataOption{Purpose: "pizza"}
+
+		serialized, err := c.SerializeWithMetadata("signed message", opt)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		var data string
+		if err := c.DeserializeWithMetadata(serialized, &data, opt); err != nil {
+			t.Errorf("legacy %v: %v", legacy, err)
+			return
+		}
+		if data != "signed message" {
+			t.Errorf("legacy %v: data mismatch: %q", legacy, data)
+		}
+
+		if err := c.DeserializeWithMetadata(serialized, &data, MetadataOption{}); !errors.Is(err, MismatchedPurposeError) {
+			t.Errorf("legacy %v: unexpected err: %v", legacy, err)
+		}
+	}
+}
+
+func TestDeserializeMissingMetadata(t *testing.T) {
+	c := New(false, false)
+
+	var data map[string]int
+	if err := c.DeserializeWithMetadata([]byte(`{"ab":123}`), &data, MetadataOption{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if data["ab"] != 123 {
+		t.Errorf("data mismatch: %v", data)
+	}
+
+	if err := c.DeserializeWithMetadata([]byte(`{"ab":123}`), &data, MetadataOption{Purpose: "pizza"}); !errors.Is(err, MismatchedPurposeError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
+
+func TestDeserializeMarshalEnvelope(t *testing.T) {
+	// Marshal.dump({ "_rails" => { "data" => "signed message", "pur" => "pizza" } })
+	src := "\x04\b{\x06I\"\v_rails\x06:\x06ET{\aI\"\tdata\x06;\x00TI\"\x13signed message\x06;\x00TI\"\bpur\x06;\x00TI\"\npizza\x06;\x00T"
+
+	c := New(false, false).WithSerializer(Marshal)
+
+	var data string
+	if err := c.DeserializeWithMetadata([]byte(src), &data, MetadataOption{Purpose: "pizza"}); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "signed message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+
+	if err := c.DeserializeWithMetadata([]byte(src), &data, MetadataOption{}); !errors.Is(err, MismatchedPurposeError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+
+	s, err := SerializerWithFallback("json_allow_marshal")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := New(false, false).WithSerializer(s).DeserializeWithMetadata([]byte(src), &data, MetadataOption{Purpose: "pizza"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeserializeMessagePackEnvelope(t *testing.T) {
+	// ActiveSupport::MessagePack.dump({ "_rails" => { "data" => "signed message", "pur" => "pizza" } })
+	src := "\xcc\x80\x81\xa6_rails\x82\xa4data\xaesigned message\xa3pur\xa5pizza"
+
+	c := New(false, false).WithSerializer(MessagePack)
+
+	var data string
+	if err := c.DeserializeWithMetadata([]byte(src), &data, MetadataOption{Purpose: "pizza"}); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "signed message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+
+	serialized, err := c.SerializeWithMetadata("signed message", MetadataOption{Purpose: "pizza"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(serialized) != src {
+		t.Errorf("serialized mismatch: %q", serialized)
+	}
+
+	s, err := SerializerWithFallback("message_pack")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	c = New(false, false).WithSerializer(s)
+	if err := c.DeserializeWithMetadata([]byte(`{"_rails":{"data":"signed message","pur":"pizza"}}`), &data, MetadataOption{Purpose: "pizza"}); err != nil {
+		t.Error(err)
+	}
+}
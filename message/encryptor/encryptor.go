@@ -8,6 +8,7 @@ import (
 	"errors"
 	"hash"
 	"io"
+	"slices"
 
 	"github.com/atitan/activesupport-go/message/codec"
 	"github.com/atitan/activesupport-go/message/verifier"
@@ -25,6 +26,9 @@ type Encryptor struct {
 	encAEADCipher bool
 	encBlock      cipher.Block
 	macVerifier   *verifier.Verifier
+	rotations     []*Encryptor
+	onRotation    func()
+	random        io.Reader
 }
 
 func New(msgCodec codec.Codec, encAEADCipher bool, encSecret []byte, hmacFunc func() hash.Hash, hmacSecret []byte) *Encryptor {
@@ -53,7 +57,65 @@ func New(msgCodec codec.Codec, encAEADCipher bool, encSecret []byte, hmacFunc fu
 	}
 }
 
+// WithRotations returns a copy of the encryptor which falls back to the given
+// encryptors, in order, when a message cannot be decrypted with its own config.
+// Messages are always encrypted with the primary config.
+func (e *Encryptor) WithRotations(fallbacks ...*Encryptor) *Encryptor {
+	rotated := *e
+	rotated.rotations = append(slices.Clone(e.rotations), fallbacks...)
+
+	return &rotated
+}
+
+// WithOnRotation returns a copy of the encryptor which calls onRotation
+// whenever a message is decrypted by one of its fallbacks.
+func (e *Encryptor) WithOnRotation(onRotation func()) *Encryptor {
+	rotated := *e
+	rotated.onRotation = onRotation
+
+	return &rotated
+}
+
+// WithRand returns a copy of the encryptor which reads IVs and nonces from r
+// instead of crypto/rand, mostly to produce deterministic output in tests.
+func (e *Encryptor) WithRand(r io.Reader) *Encryptor {
+	copied := *e
+	copied.random = r
+
+	return &copied
+}
+
+func (e *Encryptor) randReader() io.Reader {
+	if e.random == nil {
+		return rand.Reader
+	}
+
+	return e.random
+}
+
 func (e *Encryptor) Decrypt(encrypted []byte, data any, opt codec.MetadataOption) error {
+	err := e.decrypt(encrypted, data, opt)
+	if err == nil {
+		return nil
+	}
+
+	for _, rotation := range e.rotations {
+		if rotation.decrypt(encrypted, data, opt) != nil {
+			continue
+		}
+
+		if e.onRotation != nil {
+			e.onRotation()
+		}
+
+		return nil
+	}
+
+	// Report the error of the primary config like Rails does
+	return err
+}
+
+func (e *Encryptor) decrypt(encrypted []byte, data any, opt codec.MetadataOption) error {
 	var err error
 
 	if !e.encAEADCipher {
@@ -123,7 +185,7 @@ func (e *Encryptor) Encrypt(data any, opt codec.MetadataOption) ([]byte, error)
 		}
 
 		nonce := make([]byte, aesgcm.NonceSize())
-		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		if _, err := io.ReadFull(e.randReader(), nonce); err != nil {
 			return nil, err
 		}
 
@@ -138,7 +200,7 @@ func (e *Encryptor) Encrypt(data any, opt codec.MetadataOption) ([]byte, error)
 		)
 	} else {
 		iv := make([]byte, e.encBlock.BlockSize())
-		if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		if _, err := io.ReadFull(e.randReader(), iv); err != nil {
 			return nil, err
 		}
 
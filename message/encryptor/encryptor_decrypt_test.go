@@ -175,3 +175,51 @@ func TestDecryptGCM256(t *testing.T) {
 		t.Errorf("data mismatch: %q, %q", originalData, data)
 	}
 }
+
+func TestDecryptGCM256Marshal(t *testing.T) {
+	ciphertext, err := os.ReadFile("testdata/TestDecryptGCM256Marshal.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	msgCodec := codec.New(false, false).WithSerializer(codec.Marshal)
+	opt := codec.MetadataOption{}
+
+	originalData := "encrypted message"
+	var data string
+
+	e := New(msgCodec, true, []byte("12345678901234567890123456789012"), nil, nil)
+
+	if err := e.Decrypt(ciphertext, &data, opt); err != nil {
+		t.Error(err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+}
+
+func TestDecryptGCM256MessagePack(t *testing.T) {
+	ciphertext, err := os.ReadFile("testdata/TestDecryptGCM256MessagePack.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	msgCodec := codec.New(false, false).WithSerializer(codec.MessagePack)
+	opt := codec.MetadataOption{}
+
+	originalData := "encrypted message"
+	var data string
+
+	e := New(msgCodec, true, []byte("12345678901234567890123456789012"), nil, nil)
+
+	if err := e.Decrypt(ciphertext, &data, opt); err != nil {
+		t.Error(err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+}
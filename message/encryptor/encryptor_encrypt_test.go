@@ -1,6 +1,7 @@
 package encryptor
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"os"
 	"testing"
@@ -147,3 +148,79 @@ func TestEncryptGCM256(t *testing.T) {
 		return
 	}
 }
+
+func TestEncryptGCM256Marshal(t *testing.T) {
+	msgCodec := codec.New(false, false).WithSerializer(codec.Marshal)
+	opt := codec.MetadataOption{}
+
+	originalData := "encrypted message"
+
+	e := New(msgCodec, true, []byte("12345678901234567890123456789012"), nil, nil)
+
+	ciphertext, err := e.Encrypt(originalData, opt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := os.WriteFile("testdata/TestEncryptGCM256Marshal.txt", ciphertext, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestEncryptGCM256MessagePack(t *testing.T) {
+	msgCodec := codec.New(false, false).WithSerializer(codec.MessagePack)
+	opt := codec.MetadataOption{}
+
+	originalData := "encrypted message"
+
+	e := New(msgCodec, true, []byte("12345678901234567890123456789012"), nil, nil)
+
+	ciphertext, err := e.Encrypt(originalData, opt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := os.WriteFile("testdata/TestEncryptGCM256MessagePack.txt", ciphertext, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestEncryptWithRand(t *testing.T) {
+	msgCodec := codec.New(false, false)
+	opt := codec.MetadataOption{}
+
+	originalData := "encrypted message"
+
+	e := New(msgCodec, true, []byte("12345678901234567890123456789012"), nil, nil)
+
+	var outputs [][]byte
+	for i := 0; i < 2; i++ {
+		ciphertext, err := e.WithRand(bytes.NewReader(make([]byte, 12))).Encrypt(originalData, opt)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		outputs = append(outputs, ciphertext)
+	}
+
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Errorf("output mismatch: %q, %q", outputs[0], outputs[1])
+	}
+	if parts := bytes.Split(outputs[0], []byte("--")); len(parts) != 3 || string(parts[1]) != "AAAAAAAAAAAAAAAA" {
+		t.Errorf("unexpected nonce: %q", outputs[0])
+	}
+
+	var data string
+	if err := e.Decrypt(outputs[0], &data, opt); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != originalData {
+		t.Errorf("data mismatch: %q", data)
+	}
+}
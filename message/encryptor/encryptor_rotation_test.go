@@ -0,0 +1,61 @@
+package encryptor
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/atitan/activesupport-go/message/codec"
+)
+
+func TestDecryptRotatedCipher(t *testing.T) {
+	msgCodec := codec.New(false, false)
+	opt := codec.MetadataOption{Purpose: "pizza"}
+
+	originalData := "encrypted message"
+	var data string
+
+	old := New(msgCodec, false, []byte("12345678901234567890123456789012"), sha1.New, nil)
+	ciphertext, err := old.Encrypt(originalData, opt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	rotated := 0
+	e := New(msgCodec, true, []byte("abcdefghijklmnopqrstuvwxyz123456"), nil, nil).
+		WithRotations(New(msgCodec, false, []byte("12345678901234567890123456789012"), sha256.New, nil), old).
+		WithOnRotation(func() { rotated++ })
+
+	if err := e.Decrypt(ciphertext, &data, opt); err != nil {
+		t.Error(err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+	if rotated != 1 {
+		t.Errorf("on rotation called %d times", rotated)
+	}
+}
+
+func TestDecryptRotatedMismatch(t *testing.T) {
+	msgCodec := codec.New(false, false)
+	opt := codec.MetadataOption{}
+
+	var data string
+
+	ciphertext, err := New(msgCodec, true, []byte("1234567890123456"), nil, nil).Encrypt("encrypted message", opt)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	e := New(msgCodec, true, []byte("abcdefghijklmnop"), nil, nil).
+		WithRotations(New(msgCodec, false, []byte("1234567890123456"), sha256.New, nil))
+
+	if err := e.Decrypt(ciphertext, &data, opt); !errors.Is(err, InvalidMessageError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+// Package encryptors mirrors ActiveSupport::MessageEncryptors, a factory of
+// message encryptors which derives the secret of each encryptor from its salt.
+package encryptors
+
+import (
+	"crypto/sha1"
+	"hash"
+	"strings"
+
+	"github.com/atitan/activesupport-go/internal/rotation"
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/encryptor"
+)
+
+const DefaultCipher = "aes-256-gcm"
+
+// Options are the ActiveSupport::MessageEncryptor options of one rotation.
+type Options struct {
+	// Cipher is one of aes-{128,192,256}-{gcm,cbc}, and defaults to
+	// DefaultCipher.
+	Cipher string
+	// Digest signs messages encrypted in CBC mode, and defaults to sha1.New.
+	Digest func() hash.Hash
+	// Serializer defaults to the serializer of codec.New.
+	Serializer                    codec.Serializer
+	URLSafe                       bool
+	ForceLegacyMetadataSerializer bool
+
+	// SecretGenerator replaces the secret generator of the factory for this
+	// rotation, e.g. to derive secrets from a previous secret_key_base.
+	SecretGenerator func(salt string) []byte
+	// SignSecretGenerator derives the secret which signs messages encrypted
+	// in CBC mode. The encryption secret is used without one.
+	SignSecretGenerator func(salt string) []byte
+}
+
+// DefaultOptions returns the options of a Rails 7.1+ application.
+func DefaultOptions() Options {
+	serializer, err := codec.SerializerWithFallback("json_allow_marshal")
+	if err != nil {
+		panic(err)
+	}
+
+	return Options{
+		Cipher:     DefaultCipher,
+		Digest:     sha1.New,
+		Serializer: serializer,
+	}
+}
+
+// KeyLen returns the secret length of cipher, like MessageEncryptor.key_len.
+func KeyLen(cipher string) int {
+	if cipher == "" {
+		cipher = DefaultCipher
+	}
+
+	switch {
+	case strings.HasPrefix(cipher, "aes-128-"):
+		return 16
+	case strings.HasPrefix(cipher, "aes-192-"):
+		return 24
+	case strings.HasPrefix(cipher, "aes-256-"):
+		return 32
+	default:
+		panic("encryptors: unsupported cipher " + cipher)
+	}
+}
+
+type Encryptors struct {
+	secretGenerator func(salt string) []byte
+	coordinator     *rotation.Coordinator[Options, *encryptor.Encryptor]
+}
+
+// New returns a factory without any options. secretGenerator must derive a
+// key of KeyLen bytes for the cipher of each rotation.
+func New(secretGenerator func(salt string) []byte) *Encryptors {
+	if secretGenerator == nil {
+		panic("encryptors: empty secret generator")
+	}
+
+	es := &Encryptors{secretGenerator: secretGenerator}
+	es.coordinator = rotation.New(es.build)
+
+	return es
+}
+
+// Rotate appends opts to the options of every encryptor. The first options
+// are used to encrypt messages, the others only to decrypt them.
+func (es *Encryptors) Rotate(opts Options) *Encryptors {
+	return es.RotateFunc(func(string) (Options, bool) { return opts, true })
+}
+
+// RotateFunc appends per-salt options. fn may skip a salt by returning false.
+func (es *Encryptors) RotateFunc(fn func(salt string) (Options, bool)) *Encryptors {
+	es.coordinator.Rotate(fn)
+
+	return es
+}
+
+// RotateDefaults appends DefaultOptions.
+func (es *Encryptors) RotateDefaults() *Encryptors {
+	return es.Rotate(DefaultOptions())
+}
+
+// Prepend inserts opts as the options which encrypt messages.
+func (es *Encryptors) Prepend(opts Options) *Encryptors {
+	es.coordinator.Prepend(func(string) (Options, bool) { return opts, true })
+
+	return es
+}
+
+// Get returns the encryptor for salt, like message_encryptors[salt]. It
+// panics when no options apply to salt.
+func (es *Encryptors) Get(salt string) *encryptor.Encryptor {
+	return es.coordinator.Get(salt)
+}
+
+// Set overrides the encryptor for salt, like message_encryptors[salt] =.
+func (es *Encryptors) Set(salt string, e *encryptor.Encryptor) {
+	es.coordinator.Set(salt, e)
+}
+
+func (es *Encryptors) build(salt string, options []Options) *encryptor.Encryptor {
+	encryptors := make([]*encryptor.Encryptor, len(options))
+	for i, opts := range options {
+		encryptors[i] = es.buildOne(salt, opts)
+	}
+
+	return encryptors[0].WithRotations(encryptors[1:]...)
+}
+
+func (es *Encryptors) buildOne(salt string, opts Options) *encryptor.Encryptor {
+	secretGenerator := es.secretGenerator
+	if opts.SecretGenerator != nil {
+		secretGenerator = opts.SecretGenerator
+	}
+
+	cipher := opts.Cipher
+	if cipher == "" {
+		cipher = DefaultCipher
+	}
+
+	var aead bool
+	switch {
+	case strings.HasSuffix(cipher, "-gcm"):
+		aead = true
+	case strings.HasSuffix(cipher, "-cbc"):
+		aead = false
+	default:
+		panic("encryptors: unsupported cipher " + cipher)
+	}
+
+	secret := secretGenerator(salt)
+	if len(secret) != KeyLen(cipher) {
+		panic("encryptors: invalid length of secret for " + cipher)
+	}
+
+	digest := opts.Digest
+	if digest == nil {
+		digest = sha1.New
+	}
+
+	var signSecret []byte
+	if opts.SignSecretGenerator != nil {
+		signSecret = opts.SignSecretGenerator(salt)
+	}
+
+	msgCodec := codec.New(opts.URLSafe, opts.ForceLegacyMetadataSerializer)
+	if opts.Serializer != nil {
+		msgCodec = msgCodec.WithSerializer(opts.Serializer)
+	}
+
+	return encryptor.New(msgCodec, aead, secret, digest, signSecret)
+}
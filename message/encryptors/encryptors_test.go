@@ -0,0 +1,85 @@
+package encryptors
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/atitan/activesupport-go/keygenerator"
+	"github.com/atitan/activesupport-go/message/codec"
+)
+
+func secretGenerator(secretKeyBase string, keyLen int) func(salt string) []byte {
+	keyGenerator := keygenerator.New([]byte(secretKeyBase), 1000, sha256.New)
+
+	return func(salt string) []byte {
+		return keyGenerator.GenerateKey([]byte(salt), keyLen)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	es := New(secretGenerator("secret", 32)).RotateDefaults()
+
+	encrypted, err := es.Get("credit card").Encrypt("encrypted message", codec.MetadataOption{Purpose: "pizza"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var data string
+	if err := es.Get("credit card").Decrypt(encrypted, &data, codec.MetadataOption{Purpose: "pizza"}); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "encrypted message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+
+	if err := es.Get("other").Decrypt(encrypted, &data, codec.MetadataOption{Purpose: "pizza"}); err == nil {
+		t.Error("expected error for another salt")
+	}
+}
+
+func TestRotateCBC(t *testing.T) {
+	cbc := Options{
+		Cipher:              "aes-256-cbc",
+		SecretGenerator:     secretGenerator("old", 32),
+		SignSecretGenerator: secretGenerator("old", 64),
+	}
+
+	old := New(secretGenerator("unused", 32)).Rotate(cbc)
+	encrypted, err := old.Get("credit card").Encrypt("encrypted message", codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	es := New(secretGenerator("new", 32)).RotateDefaults().Rotate(cbc)
+
+	var data string
+	if err := es.Get("credit card").Decrypt(encrypted, &data, codec.MetadataOption{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "encrypted message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+}
+
+func TestKeyLen(t *testing.T) {
+	for cipher, want := range map[string]int{"": 32, "aes-128-gcm": 16, "aes-192-cbc": 24, "aes-256-cbc": 32} {
+		if got := KeyLen(cipher); got != want {
+			t.Errorf("cipher: %q; want %d; got %d", cipher, want, got)
+		}
+	}
+}
+
+func TestInvalidSecretLength(t *testing.T) {
+	es := New(secretGenerator("secret", 16)).RotateDefaults()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	es.Get("credit card")
+}
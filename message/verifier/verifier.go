@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"hash"
+	"slices"
 
 	"github.com/atitan/activesupport-go/message/codec"
 )
@@ -19,6 +20,8 @@ type Verifier struct {
 	msgCodec   codec.Codec
 	hmacFunc   func() hash.Hash
 	hmacSecret []byte
+	rotations  []*Verifier
+	onRotation func()
 }
 
 func New(msgCodec codec.Codec, hmacFunc func() hash.Hash, hmacSecret []byte) *Verifier {
@@ -36,7 +39,48 @@ func New(msgCodec codec.Codec, hmacFunc func() hash.Hash, hmacSecret []byte) *Ve
 	}
 }
 
+// WithRotations returns a copy of the verifier which falls back to the given
+// verifiers, in order, when a message cannot be verified with its own config.
+// Messages are always generated with the primary config.
+func (v *Verifier) WithRotations(fallbacks ...*Verifier) *Verifier {
+	rotated := *v
+	rotated.rotations = append(slices.Clone(v.rotations), fallbacks...)
+
+	return &rotated
+}
+
+// WithOnRotation returns a copy of the verifier which calls onRotation
+// whenever a message is verified by one of its fallbacks.
+func (v *Verifier) WithOnRotation(onRotation func()) *Verifier {
+	rotated := *v
+	rotated.onRotation = onRotation
+
+	return &rotated
+}
+
 func (v *Verifier) Verify(sealed []byte, data any, opt codec.MetadataOption) error {
+	err := v.verify(sealed, data, opt)
+	if err == nil {
+		return nil
+	}
+
+	for _, rotation := range v.rotations {
+		if rotation.verify(sealed, data, opt) != nil {
+			continue
+		}
+
+		if v.onRotation != nil {
+			v.onRotation()
+		}
+
+		return nil
+	}
+
+	// Report the error of the primary config like Rails does
+	return err
+}
+
+func (v *Verifier) verify(sealed []byte, data any, opt codec.MetadataOption) error {
 	serialized, err := v.VerifyMACAndDecode(sealed)
 	if err != nil {
 		return err
@@ -208,3 +208,123 @@ func TestGenerateExpired(t *testing.T) {
 		return
 	}
 }
+
+func TestGenerateMarshalSimpleEnvelope(t *testing.T) {
+	msgCodec := codec.New(false, false).WithSerializer(codec.Marshal)
+	opt := codec.MetadataOption{Purpose: "pizza"}
+
+	data := "signed message"
+
+	v := New(msgCodec, macHashFunc, macSecret)
+
+	sealed, err := v.Generate(data, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+
+	if err := os.WriteFile("testdata/TestGenerateMarshalSimpleEnvelope.txt", sealed, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestGenerateMarshalComplexEnvelope(t *testing.T) {
+	msgCodec := codec.New(false, false).WithSerializer(codec.Marshal)
+	opt := codec.MetadataOption{Purpose: "pizza"}
+
+	data := Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}
+
+	v := New(msgCodec, macHashFunc, macSecret)
+
+	sealed, err := v.Generate(data, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+
+	if err := os.WriteFile("testdata/TestGenerateMarshalComplexEnvelope.txt", sealed, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestGenerateMarshalLegacyComplexEnvelope(t *testing.T) {
+	msgCodec := codec.New(false, true).WithSerializer(codec.Marshal)
+	opt := codec.MetadataOption{Purpose: "pizza"}
+
+	data := Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}
+
+	v := New(msgCodec, macHashFunc, macSecret)
+
+	sealed, err := v.Generate(data, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+
+	if err := os.WriteFile("testdata/TestGenerateMarshalLegacyComplexEnvelope.txt", sealed, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestGenerateMessagePackSimpleEnvelope(t *testing.T) {
+	msgCodec := codec.New(false, false).WithSerializer(codec.MessagePack)
+	opt := codec.MetadataOption{Purpose: "pizza"}
+
+	data := "signed message"
+
+	v := New(msgCodec, macHashFunc, macSecret)
+
+	sealed, err := v.Generate(data, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+
+	if err := os.WriteFile("testdata/TestGenerateMessagePackSimpleEnvelope.txt", sealed, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestGenerateMessagePackComplexEnvelope(t *testing.T) {
+	msgCodec := codec.New(false, false).WithSerializer(codec.MessagePack)
+	opt := codec.MetadataOption{Purpose: "pizza"}
+
+	data := Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}
+
+	v := New(msgCodec, macHashFunc, macSecret)
+
+	sealed, err := v.Generate(data, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+
+	if err := os.WriteFile("testdata/TestGenerateMessagePackComplexEnvelope.txt", sealed, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestGenerateMessagePackLegacyComplexEnvelope(t *testing.T) {
+	msgCodec := codec.New(false, true).WithSerializer(codec.MessagePack)
+	opt := codec.MetadataOption{Purpose: "pizza"}
+
+	data := Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}
+
+	v := New(msgCodec, macHashFunc, macSecret)
+
+	sealed, err := v.Generate(data, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+
+	if err := os.WriteFile("testdata/TestGenerateMessagePackLegacyComplexEnvelope.txt", sealed, 0644); err != nil {
+		t.Error(err)
+		return
+	}
+}
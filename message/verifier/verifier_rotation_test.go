@@ -0,0 +1,86 @@
+package verifier
+
+import (
+	"crypto/sha1"
+	"errors"
+	"testing"
+
+	"github.com/atitan/activesupport-go/message/codec"
+)
+
+func TestVerifyRotatedSecret(t *testing.T) {
+	opt := codec.MetadataOption{Purpose: "pizza"}
+	originalData := "signed message"
+	var data string
+
+	old := New(codec.New(false, true), sha1.New, []byte("old secret"))
+	sealed, err := old.Generate(originalData, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+
+	rotated := 0
+	v := New(codec.New(true, false), macHashFunc, macSecret).
+		WithRotations(New(msgVerifyCodec, macHashFunc, []byte("unrelated")), old).
+		WithOnRotation(func() { rotated++ })
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+	if rotated != 1 {
+		t.Errorf("on rotation called %d times", rotated)
+	}
+}
+
+func TestVerifyRotatedPrimary(t *testing.T) {
+	opt := codec.MetadataOption{}
+	originalData := "signed message"
+	var data string
+
+	rotated := 0
+	v := New(msgVerifyCodec, macHashFunc, macSecret).
+		WithRotations(New(msgVerifyCodec, sha1.New, []byte("old secret"))).
+		WithOnRotation(func() { rotated++ })
+
+	sealed, err := v.Generate(originalData, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+
+	if err := New(msgVerifyCodec, macHashFunc, macSecret).Verify(sealed, &data, opt); err != nil {
+		t.Errorf("generated with fallback: %v", err)
+		return
+	}
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if rotated != 0 {
+		t.Errorf("on rotation called %d times", rotated)
+	}
+}
+
+func TestVerifyRotatedMismatch(t *testing.T) {
+	opt := codec.MetadataOption{}
+	var data string
+
+	sealed, err := New(msgVerifyCodec, macHashFunc, []byte("unknown")).Generate("signed message", opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+
+	v := New(msgVerifyCodec, macHashFunc, macSecret).
+		WithRotations(New(msgVerifyCodec, sha1.New, []byte("old secret")))
+
+	if err := v.Verify(sealed, &data, opt); !errors.Is(err, InvalidSignatureError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/testing/timehelpers"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -233,3 +235,295 @@ func TestVerifyExpired(t *testing.T) {
 		t.Errorf("unexpected err: %v", err)
 	}
 }
+
+func TestVerifyMarshalSimpleEnvelope(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyMarshalSimpleEnvelope.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	opt := codec.MetadataOption{Purpose: "pizza"}
+	originalData := "signed message"
+	var data string
+
+	v := New(msgVerifyCodec.WithSerializer(codec.Marshal), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+}
+
+func TestVerifyMarshalComplexEnvelope(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyMarshalComplexEnvelope.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	opt := codec.MetadataOption{Purpose: "pizza"}
+	originalData := Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}
+	var data Complex
+
+	v := New(msgVerifyCodec.WithSerializer(codec.Marshal), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if diff := cmp.Diff(originalData, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestVerifyMarshalLegacyComplexEnvelope(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyMarshalLegacyComplexEnvelope.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	opt := codec.MetadataOption{Purpose: "pizza"}
+	originalData := Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}
+	var data Complex
+
+	v := New(msgVerifyCodec.WithSerializer(codec.Marshal), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if diff := cmp.Diff(originalData, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestVerifyMessagePackSimpleEnvelope(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyMessagePackSimpleEnvelope.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	opt := codec.MetadataOption{Purpose: "pizza"}
+	originalData := "signed message"
+	var data string
+
+	v := New(msgVerifyCodec.WithSerializer(codec.MessagePack), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+}
+
+func TestVerifyMessagePackComplexEnvelope(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyMessagePackComplexEnvelope.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	opt := codec.MetadataOption{Purpose: "pizza"}
+	originalData := Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}
+	var data Complex
+
+	v := New(msgVerifyCodec.WithSerializer(codec.MessagePack), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if diff := cmp.Diff(originalData, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestVerifyMessagePackLegacyComplexEnvelope(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyMessagePackLegacyComplexEnvelope.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	opt := codec.MetadataOption{Purpose: "pizza"}
+	originalData := Complex{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}
+	var data Complex
+
+	v := New(msgVerifyCodec.WithSerializer(codec.MessagePack), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if diff := cmp.Diff(originalData, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestVerifyModernFrozenExpiresIn(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyModernFrozenExpiresIn.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock := timehelpers.New()
+	clock.TravelTo(time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	expiresIn := time.Hour
+	opt := codec.MetadataOption{ExpiresIn: &expiresIn, Purpose: "pizza"}
+	originalData := "signed message"
+	var data string
+
+	v := New(codec.New(false, false).WithClock(clock.Now), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+
+	generated, err := v.Generate(originalData, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+	if string(generated) != string(sealed) {
+		t.Errorf("generated mismatch: %q, %q", generated, sealed)
+	}
+
+	clock.Travel(time.Hour)
+	if err := v.Verify(sealed, &data, opt); !errors.Is(err, codec.ExpiredError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
+
+func TestVerifyLegacyFrozenExpiresIn(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyLegacyFrozenExpiresIn.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock := timehelpers.New()
+	clock.TravelTo(time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	expiresIn := time.Hour
+	opt := codec.MetadataOption{ExpiresIn: &expiresIn, Purpose: "pizza"}
+	originalData := "signed message"
+	var data string
+
+	v := New(codec.New(false, true).WithClock(clock.Now), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+
+	generated, err := v.Generate(originalData, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+	if string(generated) != string(sealed) {
+		t.Errorf("generated mismatch: %q, %q", generated, sealed)
+	}
+
+	clock.Travel(time.Hour)
+	if err := v.Verify(sealed, &data, opt); !errors.Is(err, codec.ExpiredError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
+
+func TestVerifyMarshalFrozenExpiresIn(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyMarshalFrozenExpiresIn.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock := timehelpers.New()
+	clock.TravelTo(time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	expiresIn := time.Hour
+	opt := codec.MetadataOption{ExpiresIn: &expiresIn, Purpose: "pizza"}
+	originalData := "signed message"
+	var data string
+
+	v := New(codec.New(false, false).WithSerializer(codec.Marshal).WithClock(clock.Now), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+
+	generated, err := v.Generate(originalData, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+	if string(generated) != string(sealed) {
+		t.Errorf("generated mismatch: %q, %q", generated, sealed)
+	}
+
+	clock.Travel(time.Hour)
+	if err := v.Verify(sealed, &data, opt); !errors.Is(err, codec.ExpiredError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
+
+func TestVerifyMessagePackFrozenExpiresIn(t *testing.T) {
+	sealed, err := os.ReadFile("testdata/TestVerifyMessagePackFrozenExpiresIn.txt")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	clock := timehelpers.New()
+	clock.TravelTo(time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	expiresIn := time.Hour
+	opt := codec.MetadataOption{ExpiresIn: &expiresIn, Purpose: "pizza"}
+	originalData := "signed message"
+	var data string
+
+	v := New(codec.New(false, false).WithSerializer(codec.MessagePack).WithClock(clock.Now), macHashFunc, macSecret)
+
+	if err := v.Verify(sealed, &data, opt); err != nil {
+		t.Errorf("verify: %v", err)
+		return
+	}
+	if originalData != data {
+		t.Errorf("data mismatch: %q, %q", originalData, data)
+	}
+
+	generated, err := v.Generate(originalData, opt)
+	if err != nil {
+		t.Errorf("generate: %v", err)
+		return
+	}
+	if string(generated) != string(sealed) {
+		t.Errorf("generated mismatch: %q, %q", generated, sealed)
+	}
+
+	clock.Travel(time.Hour)
+	if err := v.Verify(sealed, &data, opt); !errors.Is(err, codec.ExpiredError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+// Package verifiers mirrors ActiveSupport::MessageVerifiers, a factory of
+// message verifiers which derives the secret of each verifier from its salt.
+package verifiers
+
+import (
+	"crypto/sha1"
+	"hash"
+
+	"github.com/atitan/activesupport-go/internal/rotation"
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+// Options are the ActiveSupport::MessageVerifier options of one rotation.
+type Options struct {
+	// Digest defaults to sha1.New.
+	Digest func() hash.Hash
+	// Serializer defaults to the serializer of codec.New.
+	Serializer                    codec.Serializer
+	URLSafe                       bool
+	ForceLegacyMetadataSerializer bool
+
+	// SecretGenerator replaces the secret generator of the factory for this
+	// rotation, e.g. to derive secrets from a previous secret_key_base.
+	SecretGenerator func(salt string) []byte
+}
+
+// DefaultOptions returns the options of a Rails 7.1+ application, which
+// rotate_defaults configures.
+func DefaultOptions() Options {
+	serializer, err := codec.SerializerWithFallback("json_allow_marshal")
+	if err != nil {
+		panic(err)
+	}
+
+	return Options{
+		Digest:     sha1.New,
+		Serializer: serializer,
+	}
+}
+
+type Verifiers struct {
+	secretGenerator func(salt string) []byte
+	coordinator     *rotation.Coordinator[Options, *verifier.Verifier]
+}
+
+// New returns a factory without any options. secretGenerator usually derives
+// a 64-byte key from the salt, like Rails.application.message_verifiers does.
+func New(secretGenerator func(salt string) []byte) *Verifiers {
+	if secretGenerator == nil {
+		panic("verifiers: empty secret generator")
+	}
+
+	vs := &Verifiers{secretGenerator: secretGenerator}
+	vs.coordinator = rotation.New(vs.build)
+
+	return vs
+}
+
+// Rotate appends opts to the options of every verifier. The first options are
+// used to generate messages, the others only to verify them.
+func (vs *Verifiers) Rotate(opts Options) *Verifiers {
+	return vs.RotateFunc(func(string) (Options, bool) { return opts, true })
+}
+
+// RotateFunc appends per-salt options. fn may skip a salt by returning false.
+func (vs *Verifiers) RotateFunc(fn func(salt string) (Options, bool)) *Verifiers {
+	vs.coordinator.Rotate(fn)
+
+	return vs
+}
+
+// RotateDefaults appends DefaultOptions.
+func (vs *Verifiers) RotateDefaults() *Verifiers {
+	return vs.Rotate(DefaultOptions())
+}
+
+// Prepend inserts opts as the options which generate messages.
+func (vs *Verifiers) Prepend(opts Options) *Verifiers {
+	vs.coordinator.Prepend(func(string) (Options, bool) { return opts, true })
+
+	return vs
+}
+
+// Get returns the verifier for salt, like message_verifiers[salt]. It panics
+// when no options apply to salt.
+func (vs *Verifiers) Get(salt string) *verifier.Verifier {
+	return vs.coordinator.Get(salt)
+}
+
+// Set overrides the verifier for salt, like message_verifiers[salt] =.
+func (vs *Verifiers) Set(salt string, v *verifier.Verifier) {
+	vs.coordinator.Set(salt, v)
+}
+
+func (vs *Verifiers) build(salt string, options []Options) *verifier.Verifier {
+	verifiers := make([]*verifier.Verifier, len(options))
+	for i, opts := range options {
+		verifiers[i] = vs.buildOne(salt, opts)
+	}
+
+	return verifiers[0].WithRotations(verifiers[1:]...)
+}
+
+func (vs *Verifiers) buildOne(salt string, opts Options) *verifier.Verifier {
+	secretGenerator := vs.secretGenerator
+	if opts.SecretGenerator != nil {
+		secretGenerator = opts.SecretGenerator
+	}
+
+	digest := opts.Digest
+	if digest == nil {
+		digest = sha1.New
+	}
+
+	msgCodec := codec.New(opts.URLSafe, opts.ForceLegacyMetadataSerializer)
+	if opts.Serializer != nil {
+		msgCodec = msgCodec.WithSerializer(opts.Serializer)
+	}
+
+	return verifier.New(msgCodec, digest, secretGenerator(salt))
+}
@@ -0,0 +1,118 @@
+package verifiers
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/atitan/activesupport-go/keygenerator"
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+func secretGenerator(secretKeyBase string) func(salt string) []byte {
+	keyGenerator := keygenerator.New([]byte(secretKeyBase), 1000, sha256.New)
+
+	return func(salt string) []byte {
+		return keyGenerator.GenerateKey([]byte(salt), 64)
+	}
+}
+
+func TestGetDefaults(t *testing.T) {
+	vs := New(secretGenerator("secret")).RotateDefaults()
+
+	serializer, err := codec.SerializerWithFallback("json_allow_marshal")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	v := verifier.New(codec.New(false, false).WithSerializer(serializer), sha1.New, secretGenerator("secret")("remember_me"))
+
+	want, err := v.Generate("signed message", codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, err := vs.Get("remember_me").Generate("signed message", codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(got) != string(want) {
+		t.Errorf("output mismatch: want %q; got %q", want, got)
+	}
+
+	if vs.Get("remember_me") != vs.Get("remember_me") {
+		t.Error("verifier not cached")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	old := New(secretGenerator("old")).Rotate(Options{URLSafe: true})
+	sealed, err := old.Get("remember_me").Generate("signed message", codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	vs := New(secretGenerator("new")).
+		RotateDefaults().
+		Rotate(Options{URLSafe: true, SecretGenerator: secretGenerator("old")})
+
+	var data string
+	if err := vs.Get("remember_me").Verify(sealed, &data, codec.MetadataOption{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "signed message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+}
+
+func TestRotateFunc(t *testing.T) {
+	old := New(secretGenerator("old")).RotateDefaults()
+	sealed, err := old.Get("remember_me").Generate("signed message", codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	vs := New(secretGenerator("new")).
+		RotateDefaults().
+		RotateFunc(func(salt string) (Options, bool) {
+			opts := DefaultOptions()
+			opts.SecretGenerator = secretGenerator("old")
+			return opts, salt == "remember_me"
+		})
+
+	var data string
+	if err := vs.Get("remember_me").Verify(sealed, &data, codec.MetadataOption{}); err != nil {
+		t.Error(err)
+	}
+	if err := vs.Get("other").Verify(sealed, &data, codec.MetadataOption{}); err == nil {
+		t.Error("expected error for salt without rotation")
+	}
+}
+
+func TestSet(t *testing.T) {
+	vs := New(secretGenerator("secret")).RotateDefaults()
+
+	v := verifier.New(codec.New(false, false), sha256.New, []byte("custom"))
+	vs.Set("remember_me", v)
+
+	if vs.Get("remember_me") != v {
+		t.Error("verifier not overridden")
+	}
+}
+
+func TestRotateAfterUse(t *testing.T) {
+	vs := New(secretGenerator("secret")).RotateDefaults()
+	vs.Get("remember_me")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	vs.RotateDefaults()
+}
@@ -0,0 +1,578 @@
+package messagepack
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/atitan/activesupport-go/ruby"
+)
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) readN(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, InvalidFormatError
+	}
+
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+
+	return b, nil
+}
+
+func (d *decoder) readUint(size int) (uint64, error) {
+	b, err := d.readN(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *decoder) readLen(size int) (int, error) {
+	n, err := d.readUint(size)
+	if err != nil {
+		return 0, err
+	}
+
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, InvalidFormatError
+	}
+
+	return int(n), nil
+}
+
+func (d *decoder) readValue() (any, error) {
+	b, err := d.readN(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := b[0]; {
+	case t <= 0x7f:
+		return int64(t), nil
+	case t >= 0xe0:
+		return int64(int8(t)), nil
+	case t&0xf0 == 0x80:
+		return d.readMap(int(t & 0x0f))
+	case t&0xf0 == 0x90:
+		return d.readArray(int(t & 0x0f))
+	case t&0xe0 == 0xa0:
+		return d.readStr(int(t & 0x1f))
+	}
+
+	switch t := b[0]; t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readLen(1 << (t - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+
+		bin, err := d.readN(n)
+		return append([]byte(nil), bin...), err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readLen(1 << (t - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+
+		return d.readExt(n)
+	case 0xca:
+		u, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.readUint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.readUint(1 << (t - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+
+		if u > math.MaxInt64 {
+			return u, nil
+		}
+
+		return int64(u), nil
+	case 0xd0:
+		u, err := d.readUint(1)
+		return int64(int8(u)), err
+	case 0xd1:
+		u, err := d.readUint(2)
+		return int64(int16(u)), err
+	case 0xd2:
+		u, err := d.readUint(4)
+		return int64(int32(u)), err
+	case 0xd3:
+		u, err := d.readUint(8)
+		return int64(u), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.readExt(1 << (t - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readLen(1 << (t - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+
+		return d.readStr(n)
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (t - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, InvalidFormatError
+		}
+
+		return d.readArray(int(n))
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (t - 0xde))
+		if err != nil {
+			return nil, err
+		}
+
+		if n > uint64(len(d.data)-d.pos) {
+			return nil, InvalidFormatError
+		}
+
+		return d.readMap(int(n))
+	}
+
+	return nil, InvalidFormatError
+}
+
+func (d *decoder) readStr(n int) (any, error) {
+	b, err := d.readN(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+func (d *decoder) readArray(n int) (any, error) {
+	arr := make([]any, n)
+	for i := range arr {
+		v, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+
+		arr[i] = v
+	}
+
+	return arr, nil
+}
+
+func (d *decoder) readMap(n int) (any, error) {
+	h := &ruby.Hash{Pairs: make([]ruby.Pair, 0, n)}
+	for i := 0; i < n; i++ {
+		key, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+
+		h.Set(key, value)
+	}
+
+	return h, nil
+}
+
+func (d *decoder) readExt(n int) (any, error) {
+	t, err := d.readN(1)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := d.readN(n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch int8(t[0]) {
+	case extSymbol:
+		return ruby.Symbol(data), nil
+	case extBigint:
+		return loadBigint(data)
+	case extBigDecimal:
+		_, s, ok := strings.Cut(string(data), ":")
+		if !ok {
+			return nil, InvalidFormatError
+		}
+
+		return BigDecimal(s), nil
+	case extTimeZone:
+		return TimeZone(data), nil
+	case extURI:
+		u, err := url.Parse(string(data))
+		if err != nil {
+			return nil, InvalidFormatError
+		}
+
+		return u, nil
+	case extIPAddr:
+		addr, err := netip.ParseAddr(string(data))
+		if err != nil {
+			return nil, InvalidFormatError
+		}
+
+		return addr, nil
+	case extPathname:
+		return Pathname(data), nil
+	}
+
+	// The remaining types are packed as a stream of objects
+	sub := &decoder{data: data}
+
+	var v any
+	switch int8(t[0]) {
+	case extRational:
+		v, err = sub.readRational()
+	case extComplex:
+		v, err = sub.readComplex()
+	case extDateTime:
+		v, err = sub.readDateTime()
+	case extDate:
+		v, err = sub.readDate()
+	case extTimeWithZone:
+		v, err = sub.readTimeWithZone()
+	case extTime:
+		v, err = sub.readTime()
+	case extDuration:
+		v, err = sub.readDuration()
+	case extRange:
+		v, err = sub.readRange()
+	case extSet:
+		v, err = sub.readSet()
+	case extHashWithIndifferentAccess:
+		v, err = sub.readValue()
+		if _, ok := v.(*ruby.Hash); err == nil && !ok {
+			err = InvalidFormatError
+		}
+	case extObject:
+		v, err = sub.readObject()
+	default:
+		return nil, UnsupportedTypeError
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.pos != len(sub.data) {
+		return nil, InvalidFormatError
+	}
+
+	return v, nil
+}
+
+// loadBigint mirrors MessagePack::Bigint.from_msgpack_ext: a sign byte
+// followed by 32-bit big endian chunks, least significant first.
+func loadBigint(data []byte) (any, error) {
+	if len(data) == 0 || (len(data)-1)%4 != 0 {
+		return nil, InvalidFormatError
+	}
+
+	i := new(big.Int)
+	for pos := len(data) - 4; pos >= 1; pos -= 4 {
+		i.Lsh(i, 32)
+		i.Or(i, big.NewInt(int64(binary.BigEndian.Uint32(data[pos:pos+4]))))
+	}
+
+	if data[0] != 0 {
+		i.Neg(i)
+	}
+
+	if i.IsInt64() {
+		return i.Int64(), nil
+	}
+
+	return i, nil
+}
+
+func (d *decoder) readInt() (int64, error) {
+	v, err := d.readValue()
+	if err != nil {
+		return 0, err
+	}
+
+	i, ok := v.(int64)
+	if !ok {
+		return 0, InvalidFormatError
+	}
+
+	return i, nil
+}
+
+// readRational mirrors read_rational, where the denominator is omitted when
+// the numerator is zero.
+func (d *decoder) readRational() (*big.Rat, error) {
+	num, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	n, ok := bigOf(num)
+	if !ok {
+		return nil, InvalidFormatError
+	}
+
+	if n.Sign() == 0 {
+		return new(big.Rat), nil
+	}
+
+	den, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := bigOf(den)
+	if !ok || m.Sign() == 0 {
+		return nil, InvalidFormatError
+	}
+
+	return new(big.Rat).SetFrac(n, m), nil
+}
+
+func (d *decoder) readComplex() (any, error) {
+	re, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	im, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return Complex{Real: re, Imaginary: im}, nil
+}
+
+func (d *decoder) readDate() (any, error) {
+	jd, err := d.readInt()
+	if err != nil {
+		return nil, err
+	}
+
+	return dateOfJulianDay(jd), nil
+}
+
+func (d *decoder) readDateTime() (any, error) {
+	var fields [4]int64
+	for i := range fields {
+		v, err := d.readInt()
+		if err != nil {
+			return nil, err
+		}
+
+		fields[i] = v
+	}
+
+	fraction, err := d.readRational()
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := d.readRational()
+	if err != nil {
+		return nil, err
+	}
+
+	ns, _ := new(big.Rat).Mul(fraction, big.NewRat(int64(time.Second), 1)).Float64()
+	off, _ := new(big.Rat).Mul(offset, big.NewRat(86400, 1)).Float64()
+
+	date := dateOfJulianDay(fields[0])
+	t := time.Date(date.Year, date.Month, date.Day, int(fields[1]), int(fields[2]), int(fields[3]), int(math.Round(ns)), zoneOf(int(off)))
+
+	return DateTime(t), nil
+}
+
+func (d *decoder) readTime() (time.Time, error) {
+	sec, err := d.readInt()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	nsec, err := d.readInt()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	offset, err := d.readValue()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t := time.Unix(sec, nsec)
+
+	switch off := offset.(type) {
+	case nil:
+		return t.UTC(), nil
+	case int64:
+		return t.In(zoneOf(int(off))), nil
+	}
+
+	return time.Time{}, InvalidFormatError
+}
+
+func (d *decoder) readTimeWithZone() (any, error) {
+	local, err := d.readTime()
+	if err != nil {
+		return nil, err
+	}
+
+	zone, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := zone.(string)
+	if !ok {
+		return nil, InvalidFormatError
+	}
+
+	return TimeWithZone{Local: local, Zone: name}, nil
+}
+
+func (d *decoder) readDuration() (any, error) {
+	value, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := v.([]any)
+	if !ok || len(values) > len(durationParts) {
+		return nil, InvalidFormatError
+	}
+
+	parts := make(map[string]any)
+	for i, part := range values {
+		if part != nil {
+			parts[durationParts[i]] = part
+		}
+	}
+
+	return Duration{Value: value, Parts: parts}, nil
+}
+
+func (d *decoder) readRange() (any, error) {
+	begin, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	excludeEnd, ok := exclude.(bool)
+	if !ok {
+		return nil, InvalidFormatError
+	}
+
+	return Range{Begin: begin, End: end, ExcludeEnd: excludeEnd}, nil
+}
+
+func (d *decoder) readSet() (any, error) {
+	v, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	elems, ok := v.([]any)
+	if !ok {
+		return nil, InvalidFormatError
+	}
+
+	return Set(elems), nil
+}
+
+func (d *decoder) readObject() (any, error) {
+	loader, err := d.readInt()
+	if err != nil {
+		return nil, err
+	}
+
+	if loader != 0 && loader != 1 {
+		return nil, InvalidFormatError
+	}
+
+	class, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := class.(string)
+	if !ok {
+		return nil, InvalidFormatError
+	}
+
+	data, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Object{Class: name, JSONCreate: loader == 1, Data: data}, nil
+}
+
+// zoneOf returns UTC for a zero offset and an unnamed fixed zone otherwise.
+func zoneOf(offset int) *time.Location {
+	if offset == 0 {
+		return time.UTC
+	}
+
+	return time.FixedZone("", offset)
+}
+
+func bigOf(v any) (*big.Int, bool) {
+	switch i := v.(type) {
+	case int64:
+		return big.NewInt(i), true
+	case uint64:
+		return new(big.Int).SetUint64(i), true
+	case *big.Int:
+		return i, true
+	}
+
+	return nil, false
+}
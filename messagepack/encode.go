@@ -0,0 +1,524 @@
+package messagepack
+
+import (
+	"cmp"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/atitan/activesupport-go/internal/rubyconv"
+	"github.com/atitan/activesupport-go/ruby"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// Marshal encodes v the way ActiveSupport::MessagePack.dump does, preceded by
+// its signature. Go strings are packed as str, []byte as bin, and structs as
+// maps with String keys named after the msgpack or json tag. time.Time, big
+// numbers and the types of this package are packed with their extension type.
+func Marshal(v any) ([]byte, error) {
+	e := &encoder{}
+	e.writeUint(signatureInt)
+
+	if err := e.writeValue(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+
+	return e.buf, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) writeNil() {
+	e.buf = append(e.buf, 0xc0)
+}
+
+// writeUint and writeInt pick the smallest format like msgpack-ruby, which
+// packs non-negative integers with the unsigned formats.
+func (e *encoder) writeUint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xce), uint32(u))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcf), u)
+	}
+}
+
+func (e *encoder) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.writeUint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(int8(i)))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(int8(i)))
+	case i >= math.MinInt16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xd1), uint16(int16(i)))
+	case i >= math.MinInt32:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xd2), uint32(int32(i)))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xd3), uint64(i))
+	}
+}
+
+// writeBig packs integers beyond 64 bits with the Bigint extension, a sign
+// byte followed by 32-bit big endian chunks, least significant first.
+func (e *encoder) writeBig(i *big.Int) {
+	switch {
+	case i.IsInt64():
+		e.writeInt(i.Int64())
+		return
+	case i.IsUint64():
+		e.writeUint(i.Uint64())
+		return
+	}
+
+	data := []byte{0}
+	if i.Sign() < 0 {
+		data[0] = 1
+	}
+
+	mask := big.NewInt(math.MaxUint32)
+	chunk := new(big.Int)
+	for x := new(big.Int).Abs(i); x.Sign() > 0; x.Rsh(x, 32) {
+		chunk.And(x, mask)
+		data = binary.BigEndian.AppendUint32(data, uint32(chunk.Uint64()))
+	}
+
+	e.writeExt(extBigint, data)
+}
+
+func (e *encoder) writeFloat(f float64) {
+	e.buf = binary.BigEndian.AppendUint64(append(e.buf, 0xcb), math.Float64bits(f))
+}
+
+func (e *encoder) writeStr(s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xda), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdb), uint32(n))
+	}
+
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) writeBin(b []byte) {
+	n := len(b)
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xc5), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xc6), uint32(n))
+	}
+
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeArrayHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xdc), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdd), uint32(n))
+	}
+}
+
+func (e *encoder) writeMapHeader(n int) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xde), uint16(n))
+	default:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xdf), uint32(n))
+	}
+}
+
+func (e *encoder) writeExt(t int8, data []byte) {
+	n := len(data)
+	switch n {
+	case 1:
+		e.buf = append(e.buf, 0xd4)
+	case 2:
+		e.buf = append(e.buf, 0xd5)
+	case 4:
+		e.buf = append(e.buf, 0xd6)
+	case 8:
+		e.buf = append(e.buf, 0xd7)
+	case 16:
+		e.buf = append(e.buf, 0xd8)
+	default:
+		switch {
+		case n <= math.MaxUint8:
+			e.buf = append(e.buf, 0xc7, byte(n))
+		case n <= math.MaxUint16:
+			e.buf = binary.BigEndian.AppendUint16(append(e.buf, 0xc8), uint16(n))
+		default:
+			e.buf = binary.BigEndian.AppendUint32(append(e.buf, 0xc9), uint32(n))
+		}
+	}
+
+	e.buf = append(e.buf, byte(t))
+	e.buf = append(e.buf, data...)
+}
+
+// writeRecursiveExt packs the objects written by fn as the payload of an
+// extension, for the types registered as recursive by Rails.
+func (e *encoder) writeRecursiveExt(t int8, fn func(sub *encoder) error) error {
+	sub := &encoder{}
+	if err := fn(sub); err != nil {
+		return err
+	}
+
+	e.writeExt(t, sub.buf)
+	return nil
+}
+
+// writeRational mirrors write_rational, which omits the denominator when the
+// numerator is zero.
+func (e *encoder) writeRational(r *big.Rat) {
+	e.writeBig(r.Num())
+	if r.Sign() != 0 {
+		e.writeBig(r.Denom())
+	}
+}
+
+func (e *encoder) writeTime(t time.Time) {
+	_, offset := t.Zone()
+
+	e.writeInt(t.Unix())
+	e.writeInt(int64(t.Nanosecond()))
+	e.writeInt(int64(offset))
+}
+
+func (e *encoder) writeDateTime(t time.Time) {
+	_, offset := t.Zone()
+	date := Date{Year: t.Year(), Month: t.Month(), Day: t.Day()}
+
+	e.writeInt(date.julianDay())
+	e.writeInt(int64(t.Hour()))
+	e.writeInt(int64(t.Minute()))
+	e.writeInt(int64(t.Second()))
+	e.writeRational(big.NewRat(int64(t.Nanosecond()), int64(time.Second)))
+	e.writeRational(big.NewRat(int64(offset), 86400))
+}
+
+func (e *encoder) writeHash(h *ruby.Hash) error {
+	// Hash defaults have no representation in MessagePack
+	e.writeMapHeader(len(h.Pairs))
+	for _, pair := range h.Pairs {
+		if err := e.writeValue(reflect.ValueOf(pair.Key)); err != nil {
+			return err
+		}
+
+		if err := e.writeValue(reflect.ValueOf(pair.Value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *encoder) writeMap(v reflect.Value) error {
+	keys := v.MapKeys()
+	slices.SortFunc(keys, compareKeys)
+
+	e.writeMapHeader(len(keys))
+	for _, key := range keys {
+		if err := e.writeValue(key); err != nil {
+			return err
+		}
+
+		if err := e.writeValue(v.MapIndex(key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// compareKeys sorts map keys so the output is deterministic.
+func compareKeys(a, b reflect.Value) int {
+	for a.Kind() == reflect.Interface && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface && !b.IsNil() {
+		b = b.Elem()
+	}
+
+	if a.Kind() == b.Kind() {
+		switch a.Kind() {
+		case reflect.String:
+			return cmp.Compare(a.String(), b.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return cmp.Compare(a.Int(), b.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return cmp.Compare(a.Uint(), b.Uint())
+		}
+	}
+
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func (e *encoder) writeStruct(v reflect.Value) error {
+	fields := rubyconv.Fields(v.Type(), "msgpack")
+
+	present := make([]rubyconv.Field, 0, len(fields))
+	for _, field := range fields {
+		if field.OmitEmpty && v.FieldByIndex(field.Index).IsZero() {
+			continue
+		}
+
+		present = append(present, field)
+	}
+
+	e.writeMapHeader(len(present))
+	for _, field := range present {
+		e.writeStr(field.Name)
+
+		if err := e.writeValue(v.FieldByIndex(field.Index)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *encoder) writeValues(values ...any) error {
+	for _, v := range values {
+		if err := e.writeValue(reflect.ValueOf(v)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeExtension packs the Ruby types with an extension type registered by
+// ActiveSupport::MessagePack::Extensions. It reports false for other values.
+func (e *encoder) writeExtension(v any) (bool, error) {
+	switch x := v.(type) {
+	case ruby.Symbol:
+		e.writeExt(extSymbol, []byte(x))
+	case *big.Int:
+		if x == nil {
+			e.writeNil()
+			break
+		}
+
+		e.writeBig(x)
+	case big.Int:
+		e.writeBig(&x)
+	case BigDecimal:
+		e.writeExt(extBigDecimal, []byte(strconv.Itoa(bigDecimalPrecision(x))+":"+string(x)))
+	case *big.Rat:
+		if x == nil {
+			e.writeNil()
+			break
+		}
+
+		return true, e.writeRecursiveExt(extRational, func(sub *encoder) error {
+			sub.writeRational(x)
+			return nil
+		})
+	case big.Rat:
+		return e.writeExtension(&x)
+	case Complex:
+		return true, e.writeRecursiveExt(extComplex, func(sub *encoder) error {
+			return sub.writeValues(x.Real, x.Imaginary)
+		})
+	case DateTime:
+		return true, e.writeRecursiveExt(extDateTime, func(sub *encoder) error {
+			sub.writeDateTime(time.Time(x))
+			return nil
+		})
+	case Date:
+		return true, e.writeRecursiveExt(extDate, func(sub *encoder) error {
+			sub.writeInt(x.julianDay())
+			return nil
+		})
+	case TimeWithZone:
+		return true, e.writeRecursiveExt(extTimeWithZone, func(sub *encoder) error {
+			sub.writeTime(x.Local)
+			sub.writeStr(x.Zone)
+			return nil
+		})
+	case time.Time:
+		return true, e.writeRecursiveExt(extTime, func(sub *encoder) error {
+			sub.writeTime(x)
+			return nil
+		})
+	case TimeZone:
+		e.writeExt(extTimeZone, []byte(x))
+	case Duration:
+		return true, e.writeRecursiveExt(extDuration, func(sub *encoder) error {
+			parts := make([]any, len(durationParts))
+			for i, part := range durationParts {
+				parts[i] = x.Parts[part]
+			}
+
+			return sub.writeValues(x.Value, parts)
+		})
+	case Range:
+		return true, e.writeRecursiveExt(extRange, func(sub *encoder) error {
+			return sub.writeValues(x.Begin, x.End, x.ExcludeEnd)
+		})
+	case Set:
+		return true, e.writeRecursiveExt(extSet, func(sub *encoder) error {
+			return sub.writeValues([]any(x))
+		})
+	case *url.URL:
+		if x == nil {
+			e.writeNil()
+			break
+		}
+
+		e.writeExt(extURI, []byte(x.String()))
+	case url.URL:
+		e.writeExt(extURI, []byte(x.String()))
+	case netip.Addr:
+		e.writeExt(extIPAddr, []byte(x.String()))
+	case Pathname:
+		e.writeExt(extPathname, []byte(x))
+	case HashWithIndifferentAccess:
+		return true, e.writeRecursiveExt(extHashWithIndifferentAccess, func(sub *encoder) error {
+			return sub.writeValues(map[string]any(x))
+		})
+	case *Object:
+		if x == nil {
+			e.writeNil()
+			break
+		}
+
+		return e.writeExtension(*x)
+	case Object:
+		return true, e.writeRecursiveExt(extObject, func(sub *encoder) error {
+			loader := 0
+			if x.JSONCreate {
+				loader = 1
+			}
+
+			return sub.writeValues(loader, x.Class, x.Data)
+		})
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (e *encoder) writeValue(v reflect.Value) error {
+	if !v.IsValid() {
+		e.writeNil()
+		return nil
+	}
+
+	if ok, err := e.writeExtension(v.Interface()); ok || err != nil {
+		return err
+	}
+
+	switch x := v.Interface().(type) {
+	case *ruby.Hash:
+		if x == nil {
+			e.writeNil()
+			return nil
+		}
+
+		return e.writeHash(x)
+	case ruby.Hash:
+		return e.writeHash(&x)
+	case []byte:
+		if x == nil {
+			e.writeNil()
+			return nil
+		}
+
+		e.writeBin(x)
+		return nil
+	}
+
+	if v.Type().Implements(textMarshalerType) && v.Kind() != reflect.Pointer {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return err
+		}
+
+		e.writeStr(string(text))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			e.writeNil()
+			return nil
+		}
+
+		return e.writeValue(v.Elem())
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		e.writeFloat(v.Float())
+	case reflect.String:
+		e.writeStr(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			e.writeNil()
+			return nil
+		}
+
+		e.writeArrayHeader(v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := e.writeValue(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.writeNil()
+			return nil
+		}
+
+		return e.writeMap(v)
+	case reflect.Struct:
+		return e.writeStruct(v)
+	default:
+		return fmt.Errorf("%w: %s", UnsupportedTypeError, v.Type())
+	}
+
+	return nil
+}
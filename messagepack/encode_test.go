@@ -0,0 +1,156 @@
+package messagepack
+
+import (
+	"math/big"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/ruby"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMarshalString(t *testing.T) {
+	out, err := Marshal("signed message")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(out) != "\xcc\x80\xaesigned message" {
+		t.Errorf("data mismatch: %q", out)
+	}
+}
+
+func TestMarshalIntegers(t *testing.T) {
+	integers := map[int64]string{
+		0:          "\xcc\x80\x00",
+		127:        "\xcc\x80\x7f",
+		128:        "\xcc\x80\xcc\x80",
+		256:        "\xcc\x80\xcd\x01\x00",
+		-1:         "\xcc\x80\xff",
+		-33:        "\xcc\x80\xd0\xdf",
+		-256:       "\xcc\x80\xd1\xff\x00",
+		1167609600: "\xcc\x80\xce\x45\x98\x4f\x00",
+	}
+
+	for src, want := range integers {
+		out, err := Marshal(src)
+		if err != nil {
+			t.Errorf("input: %d; %v", src, err)
+			continue
+		}
+		if string(out) != want {
+			t.Errorf("input: %d; want %q; got: %q", src, want, out)
+		}
+	}
+}
+
+func TestMarshalBigint(t *testing.T) {
+	out, err := Marshal(new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if want := "\xcc\x80\xc7\x0d\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01"; string(out) != want {
+		t.Errorf("data mismatch: %q", out)
+	}
+}
+
+func TestMarshalComplexStruct(t *testing.T) {
+	out, err := Marshal(ComplexHash{Ab: 123, Cd: "yellow", Ef: true, Gh: nil})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if want := "\xcc\x80\x84\xa2ab\x7b\xa2cd\xa6yellow\xa2ef\xc3\xa2gh\xc0"; string(out) != want {
+		t.Errorf("data mismatch: %q", out)
+	}
+}
+
+func TestMarshalSymbolMap(t *testing.T) {
+	out, err := Marshal(map[ruby.Symbol]any{"b": nil, "a": 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if want := "\xcc\x80\x82\xd4\x00a\x01\xd4\x00b\xc0"; string(out) != want {
+		t.Errorf("data mismatch: %q", out)
+	}
+}
+
+func TestMarshalTime(t *testing.T) {
+	out, err := Marshal(time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if want := "\xcc\x80\xc7\x07\x08\xce\x45\x98\x4f\x00\x00\x00"; string(out) != want {
+		t.Errorf("data mismatch: %q", out)
+	}
+}
+
+func TestMarshalExtensionRoundTrip(t *testing.T) {
+	taipei := time.FixedZone("", 8*3600)
+	u, _ := url.Parse("https://example.com/a?b=c")
+
+	values := []any{
+		ruby.Symbol("foo"),
+		BigDecimal("0.15e1"),
+		Complex{Real: int64(1), Imaginary: int64(2)},
+		DateTime(time.Date(2007, 1, 1, 4, 5, 6, 500000000, taipei)),
+		Date{Year: 1999, Month: time.December, Day: 31},
+		TimeWithZone{Local: time.Date(2007, 1, 1, 0, 0, 0, 123456789, taipei), Zone: "Taipei"},
+		TimeZone("Taipei"),
+		Duration{Value: int64(93600), Parts: map[string]any{"days": int64(1), "hours": int64(2)}},
+		Range{Begin: int64(1), End: nil, ExcludeEnd: true},
+		Set{"a", int64(1)},
+		u,
+		netip.MustParseAddr("::1"),
+		Pathname("/tmp"),
+		&Object{Class: "Money", JSONCreate: true, Data: "1.00"},
+	}
+
+	opts := []cmp.Option{
+		cmp.Comparer(func(a, b time.Time) bool { return a.Equal(b) }),
+		cmp.Comparer(func(a, b DateTime) bool { return a.Time().Equal(b.Time()) }),
+		cmp.Comparer(func(a, b netip.Addr) bool { return a == b }),
+	}
+
+	for _, value := range values {
+		out, err := Marshal(value)
+		if err != nil {
+			t.Errorf("input: %#v; %v", value, err)
+			continue
+		}
+
+		loaded, err := Load(out)
+		if err != nil {
+			t.Errorf("input: %#v; %v", value, err)
+			continue
+		}
+
+		if diff := cmp.Diff(value, loaded, opts...); diff != "" {
+			t.Errorf("data mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestMarshalRational(t *testing.T) {
+	for _, r := range []*big.Rat{big.NewRat(1, 3), new(big.Rat)} {
+		out, err := Marshal(r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		var data big.Rat
+		if err := Unmarshal(out, &data); err != nil {
+			t.Error(err)
+			return
+		}
+		if data.Cmp(r) != 0 {
+			t.Errorf("data mismatch: %s", data.String())
+		}
+	}
+}
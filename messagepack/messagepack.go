@@ -0,0 +1,64 @@
+// Package messagepack reads and writes the MessagePack format of
+// ActiveSupport::MessagePack, including its extension types for Ruby and
+// Rails classes. Payloads start with the packed integer 128 as a signature.
+package messagepack
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/atitan/activesupport-go/internal/rubyconv"
+)
+
+// signatureInt is written ahead of every object, packed as "\xCC\x80".
+const signatureInt = 128
+
+var (
+	InvalidFormatError   = errors.New("messagepack: invalid format")
+	UnsupportedTypeError = errors.New("messagepack: unsupported type")
+)
+
+// Unmarshal decodes an ActiveSupport::MessagePack payload into v following
+// the rules of encoding/json. Maps are decoded into structs by matching their
+// String or Symbol keys against the msgpack tag, then the json tag, then field
+// names.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("messagepack: Unmarshal(%T)", v)
+	}
+
+	value, err := Load(data)
+	if err != nil {
+		return err
+	}
+
+	if err := rubyconv.Assign(rv.Elem(), value, "msgpack"); err != nil {
+		return fmt.Errorf("messagepack: %w", err)
+	}
+
+	return nil
+}
+
+// Load decodes an ActiveSupport::MessagePack payload into its raw Ruby
+// representation, with maps kept as ordered *ruby.Hash.
+func Load(data []byte) (any, error) {
+	d := &decoder{data: data}
+
+	sig, err := d.readValue()
+	if err != nil || sig != int64(signatureInt) {
+		return nil, InvalidFormatError
+	}
+
+	v, err := d.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	if d.pos != len(d.data) {
+		return nil, InvalidFormatError
+	}
+
+	return v, nil
+}
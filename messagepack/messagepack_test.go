@@ -0,0 +1,256 @@
+package messagepack
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/ruby"
+	"github.com/google/go-cmp/cmp"
+)
+
+type ComplexHash struct {
+	Ab int    `json:"ab"`
+	Cd string `json:"cd"`
+	Ef bool   `json:"ef"`
+	Gh *int   `json:"gh"`
+}
+
+func TestUnmarshalString(t *testing.T) {
+	var data string
+	if err := Unmarshal([]byte("\xcc\x80\xaesigned message"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "signed message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+}
+
+func TestUnmarshalBinary(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\xcc\x80\xc4\x02\x00\xff"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff([]byte{0x00, 0xff}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalIntegers(t *testing.T) {
+	integers := map[string]int64{
+		"\xcc\x80\x00":                 0,
+		"\xcc\x80\x7f":                 127,
+		"\xcc\x80\xcc\x80":             128,
+		"\xcc\x80\xcd\x01\x00":         256,
+		"\xcc\x80\xff":                 -1,
+		"\xcc\x80\xd0\x80":             -128,
+		"\xcc\x80\xd1\xff\x00":         -256,
+		"\xcc\x80\xce\x45\x98\x4f\x00": 1167609600,
+	}
+
+	for src, want := range integers {
+		var data int64
+		if err := Unmarshal([]byte(src), &data); err != nil {
+			t.Errorf("input: %q; %v", src, err)
+			continue
+		}
+		if data != want {
+			t.Errorf("input: %q; want %d; got: %d", src, want, data)
+		}
+	}
+}
+
+func TestUnmarshalBigint(t *testing.T) {
+	var data *big.Int
+	if err := Unmarshal([]byte("\xcc\x80\xc7\x0d\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if want := new(big.Int).Lsh(big.NewInt(1), 64); data.Cmp(want) != 0 {
+		t.Errorf("data mismatch: %s", data)
+	}
+}
+
+func TestUnmarshalSymbol(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\xcc\x80\xc7\x03\x00foo"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != ruby.Symbol("foo") {
+		t.Errorf("data mismatch: %#v", data)
+	}
+}
+
+func TestUnmarshalComplexHash(t *testing.T) {
+	src := "\xcc\x80\x84\xa2ab\x7b\xa2cd\xa6yellow\xa2ef\xc3\xa2gh\xc0"
+
+	var data ComplexHash
+	if err := Unmarshal([]byte(src), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(ComplexHash{Ab: 123, Cd: "yellow", Ef: true, Gh: nil}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalSymbolKeys(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\xcc\x80\x81\xd4\x00a\x01"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(map[string]any{"a": int64(1)}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalTime(t *testing.T) {
+	var data time.Time
+	if err := Unmarshal([]byte("\xcc\x80\xc7\x07\x08\xce\x45\x98\x4f\x00\x00\x00"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if want := time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC); !data.Equal(want) || data.Location() != time.UTC {
+		t.Errorf("data mismatch: %v", data)
+	}
+}
+
+func TestUnmarshalTimeWithZone(t *testing.T) {
+	src := "\xcc\x80\xc7\x15\x07\xce\x45\x97\xde\x80\x00\xcd\x70\x80\xabAsia/Taipei"
+
+	var data any
+	if err := Unmarshal([]byte(src), &data); err != nil {
+		t.Error(err)
+		return
+	}
+
+	twz, ok := data.(TimeWithZone)
+	if !ok {
+		t.Errorf("data mismatch: %#v", data)
+		return
+	}
+	if want := time.Date(2007, 1, 1, 0, 0, 0, 0, time.FixedZone("", 8*3600)); !twz.Local.Equal(want) || twz.Zone != "Asia/Taipei" {
+		t.Errorf("data mismatch: %v", twz)
+	}
+	if _, offset := twz.Local.Zone(); offset != 28800 {
+		t.Errorf("offset mismatch: %d", offset)
+	}
+}
+
+func TestUnmarshalDate(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\xcc\x80\xc7\x05\x06\xce\x00\x25\x72\x56"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(Date{Year: 2007, Month: time.January, Day: 1}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+
+	var tm time.Time
+	if err := Unmarshal([]byte("\xcc\x80\xc7\x05\x06\xce\x00\x25\x72\x56"), &tm); err != nil {
+		t.Error(err)
+		return
+	}
+	if want := time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC); !tm.Equal(want) {
+		t.Errorf("data mismatch: %v", tm)
+	}
+}
+
+func TestUnmarshalDateTime(t *testing.T) {
+	// DateTime.new(2007, 1, 1, 4, 5, 6.5, "+08:00")
+	src := "\xcc\x80\xc7\x0c\x05\xce\x00\x25\x72\x56\x04\x05\x06\x01\x02\x01\x03"
+
+	var data time.Time
+	if err := Unmarshal([]byte(src), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if want := time.Date(2007, 1, 1, 4, 5, 6, 500000000, time.FixedZone("", 8*3600)); !data.Equal(want) {
+		t.Errorf("data mismatch: %v", data)
+	}
+	if _, offset := data.Zone(); offset != 28800 {
+		t.Errorf("offset mismatch: %d", offset)
+	}
+}
+
+func TestUnmarshalRational(t *testing.T) {
+	var data big.Rat
+	if err := Unmarshal([]byte("\xcc\x80\xd5\x03\x01\x03"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data.Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("data mismatch: %s", data.String())
+	}
+}
+
+func TestUnmarshalBigDecimal(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\xcc\x80\xc7\x09\x0218:0.15e1"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != BigDecimal("0.15e1") {
+		t.Errorf("data mismatch: %#v", data)
+		return
+	}
+
+	if r, ok := data.(BigDecimal).Rat(); !ok || r.Cmp(big.NewRat(3, 2)) != 0 {
+		t.Errorf("rat mismatch: %v", r)
+	}
+}
+
+func TestUnmarshalDuration(t *testing.T) {
+	// 1.day + 2.hours
+	src := "\xcc\x80\xc7\x0d\x0a\xce\x00\x01\x6d\xa0\x97\xc0\xc0\xc0\x01\x02\xc0\xc0"
+
+	var data any
+	if err := Unmarshal([]byte(src), &data); err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := Duration{Value: int64(93600), Parts: map[string]any{"days": int64(1), "hours": int64(2)}}
+	if diff := cmp.Diff(want, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalSet(t *testing.T) {
+	var data []string
+	if err := Unmarshal([]byte("\xcc\x80\xc7\x05\x0c\x92\xa1a\xa1b"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff([]string{"a", "b"}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalHashWithIndifferentAccess(t *testing.T) {
+	var data map[string]int
+	if err := Unmarshal([]byte("\xcc\x80\xd6\x10\x81\xa1a\x01"), &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if diff := cmp.Diff(map[string]int{"a": 1}, data); diff != "" {
+		t.Errorf("data mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUnmarshalInvalidSignature(t *testing.T) {
+	var data any
+	if err := Unmarshal([]byte("\xaesigned message"), &data); !errors.Is(err, InvalidFormatError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+
+	if err := Unmarshal([]byte("\xcc\x80\x01\x02"), &data); !errors.Is(err, InvalidFormatError) {
+		t.Errorf("unexpected err: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package messagepack
+
+import (
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Extension type codes registered by ActiveSupport::MessagePack::Extensions.
+const (
+	extSymbol                    = 0
+	extBigint                    = 1
+	extBigDecimal                = 2
+	extRational                  = 3
+	extComplex                   = 4
+	extDateTime                  = 5
+	extDate                      = 6
+	extTimeWithZone              = 7
+	extTime                      = 8
+	extTimeZone                  = 9
+	extDuration                  = 10
+	extRange                     = 11
+	extSet                       = 12
+	extURI                       = 13
+	extIPAddr                    = 14
+	extPathname                  = 15
+	extHashWithIndifferentAccess = 16
+	extObject                    = 127
+)
+
+// unixEpochJD is the Julian Day Number of 1970-01-01.
+const unixEpochJD = 2440588
+
+// durationParts are the parts of ActiveSupport::Duration, in packing order.
+var durationParts = []string{"years", "months", "weeks", "days", "hours", "minutes", "seconds"}
+
+// BigDecimal holds a BigDecimal as formatted by BigDecimal#to_s, like "0.15e1".
+type BigDecimal string
+
+// Rat returns the exact value of d, or false if d is not finite.
+func (d BigDecimal) Rat() (*big.Rat, bool) {
+	return new(big.Rat).SetString(string(d))
+}
+
+// Complex is a Ruby Complex, whose parts are integers, floats or *big.Rat.
+type Complex struct {
+	Real      any
+	Imaginary any
+}
+
+// Date is a Ruby Date. Dates before the Gregorian reform of 1582 are not
+// converted to the Julian calendar like Ruby does.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// Time returns the midnight of d in UTC.
+func (d Date) Time() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+func (d Date) julianDay() int64 {
+	return d.Time().Unix()/86400 + unixEpochJD
+}
+
+func dateOfJulianDay(jd int64) Date {
+	y, m, d := time.Unix((jd-unixEpochJD)*86400, 0).UTC().Date()
+
+	return Date{Year: y, Month: m, Day: d}
+}
+
+// DateTime is a Ruby DateTime, which unlike time.Time is packed with its
+// local calendar date and a fractional offset.
+type DateTime time.Time
+
+func (d DateTime) Time() time.Time {
+	return time.Time(d)
+}
+
+// TimeWithZone is an ActiveSupport::TimeWithZone. Zone is the name of its
+// ActiveSupport::TimeZone, which is either a Rails zone name like "Eastern
+// Time (US & Canada)" or an IANA identifier.
+type TimeWithZone struct {
+	Local time.Time
+	Zone  string
+}
+
+func (t TimeWithZone) Time() time.Time {
+	return t.Local
+}
+
+// TimeZone is the name of an ActiveSupport::TimeZone.
+type TimeZone string
+
+// Duration is an ActiveSupport::Duration. Parts is keyed by years, months,
+// weeks, days, hours, minutes and seconds.
+type Duration struct {
+	Value any
+	Parts map[string]any
+}
+
+type Range struct {
+	Begin      any
+	End        any
+	ExcludeEnd bool
+}
+
+type Set []any
+
+type Pathname string
+
+// HashWithIndifferentAccess is packed as an
+// ActiveSupport::HashWithIndifferentAccess. Unpacked ones are surfaced as
+// plain hashes.
+type HashWithIndifferentAccess map[string]any
+
+// Object is an instance of a class which ActiveSupport::MessagePack packs
+// through either to_msgpack_ext, or as_json when JSONCreate is set.
+type Object struct {
+	Class      string
+	JSONCreate bool
+	Data       any
+}
+
+// bigDecimalPrecision guesses the precision prefix of BigDecimal#_dump,
+// which counts 9 digit words. BigDecimal._load only uses it as an upper bound.
+func bigDecimalPrecision(d BigDecimal) int {
+	mantissa, _, _ := strings.Cut(strings.ToLower(string(d)), "e")
+
+	digits := 0
+	for _, c := range strings.TrimLeft(mantissa, "+-0.") {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+
+	return ((digits+8)/9 + 1) * 9
+}
@@ -0,0 +1,34 @@
+package rails
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/atitan/activesupport-go/encryptedconfiguration"
+)
+
+// MasterKeyEnv is the environment variable which overrides the key file of
+// the credentials.
+const MasterKeyEnv = "RAILS_MASTER_KEY"
+
+// Credentials returns Rails.application.credentials of the app in root for
+// env. Like Rails, config/credentials/<env>.yml.enc and
+// config/credentials/<env>.key are used together when the former exists,
+// and config/credentials.yml.enc and config/master.key otherwise.
+// requireMasterKey mirrors config.require_master_key.
+func Credentials(root, env string, requireMasterKey bool) *encryptedconfiguration.Configuration {
+	contentPath := filepath.Join(root, "config", "credentials", env+".yml.enc")
+	keyPath := filepath.Join(root, "config", "credentials", env+".key")
+
+	if !exists(contentPath) {
+		contentPath = filepath.Join(root, "config", "credentials.yml.enc")
+		keyPath = filepath.Join(root, "config", "master.key")
+	}
+
+	return encryptedconfiguration.New(contentPath, keyPath, MasterKeyEnv, requireMasterKey)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
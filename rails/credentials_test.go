@@ -0,0 +1,74 @@
+package rails
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentials(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(MasterKeyEnv, "")
+
+	if err := os.MkdirAll(filepath.Join(root, "config", "credentials"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "config", "master.key"), []byte("4aa19bef10a27fd29e09058b10e8c279"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "config", "credentials", "production.key"), []byte("b3c631c314c0bbca50c1b2843150fe33"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Credentials(root, "development", false).Write([]byte("env: shared\n")); err != nil {
+		t.Error(err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(root, "config", "credentials", "production.yml.enc"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Credentials(root, "production", false).Write([]byte("env: production\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for env, want := range map[string]string{"development": "shared", "test": "shared", "production": "production"} {
+		credentials := Credentials(root, env, false)
+
+		value, err := credentials.FetchString("env")
+		if err != nil {
+			t.Errorf("env: %s; %v", env, err)
+			continue
+		}
+		if value != want {
+			t.Errorf("env: %s; want %q; got %q", env, want, value)
+		}
+	}
+
+	if filepath.Base(Credentials(root, "production", false).KeyPath) != "production.key" {
+		t.Error("per-environment key not preferred")
+	}
+}
+
+func TestCredentialsMixedFiles(t *testing.T) {
+	root := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(root, "config", "credentials"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"staging.key", "test.yml.enc"} {
+		if err := os.WriteFile(filepath.Join(root, "config", "credentials", name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for env, want := range map[string][2]string{
+		"staging": {"config/credentials.yml.enc", "config/master.key"},
+		"test":    {"config/credentials/test.yml.enc", "config/credentials/test.key"},
+	} {
+		credentials := Credentials(root, env, false)
+		if got := [2]string{credentials.ContentPath, credentials.KeyPath}; got != [2]string{filepath.Join(root, want[0]), filepath.Join(root, want[1])} {
+			t.Errorf("env: %s; unexpected paths: %v", env, got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Package rails derives message verifiers and encryptors from a
+// secret_key_base the same way a Rails application does.
+package rails
+
+import (
+	"hash"
+
+	"github.com/atitan/activesupport-go/actiondispatch/cookies"
+	"github.com/atitan/activesupport-go/activerecord/signedid"
+	"github.com/atitan/activesupport-go/activerecord/tokenfor"
+	"github.com/atitan/activesupport-go/activestorage"
+	"github.com/atitan/activesupport-go/globalid"
+	"github.com/atitan/activesupport-go/keygenerator"
+	"github.com/atitan/activesupport-go/message/encryptor"
+	"github.com/atitan/activesupport-go/message/encryptors"
+	"github.com/atitan/activesupport-go/message/verifier"
+	"github.com/atitan/activesupport-go/message/verifiers"
+	"github.com/atitan/activesupport-go/turbo"
+)
+
+// KeyIterations is the PBKDF2 iteration count of Rails.application.key_generator.
+const KeyIterations = 1000
+
+type Application struct {
+	keyGenerator      *keygenerator.CachingKeyGenerator
+	messageVerifiers  *verifiers.Verifiers
+	messageEncryptors *encryptors.Encryptors
+}
+
+// New returns an application keyed by secretKeyBase. keyDigest is the
+// config.active_support.key_generator_hash_digest_class of the Rails app,
+// sha256.New since Rails 7.0 and sha1.New before.
+func New(secretKeyBase []byte, keyDigest func() hash.Hash) *Application {
+	keyGenerator := keygenerator.NewCaching(keygenerator.New(secretKeyBase, KeyIterations, keyDigest))
+
+	messageVerifiers := verifiers.New(func(salt string) []byte {
+		return keyGenerator.GenerateKey([]byte(salt), 64)
+	}).RotateDefaults()
+
+	messageEncryptors := encryptors.New(func(salt string) []byte {
+		return keyGenerator.GenerateKey([]byte(salt), encryptors.KeyLen(encryptors.DefaultCipher))
+	}).RotateDefaults()
+
+	return &Application{
+		keyGenerator:      keyGenerator,
+		messageVerifiers:  messageVerifiers,
+		messageEncryptors: messageEncryptors,
+	}
+}
+
+// KeyGenerator returns the generator of Rails.application.key_generator,
+// which caches derived keys.
+func (a *Application) KeyGenerator() *keygenerator.CachingKeyGenerator {
+	return a.keyGenerator
+}
+
+// CookiesConfig returns the default cookie settings, with keys derived by the
+// key generator of the application.
+func (a *Application) CookiesConfig() cookies.Config {
+	return cookies.NewConfig(a.keyGenerator)
+}
+
+// MessageVerifiers returns the factory of Rails.application.message_verifiers.
+// Its options may be rotated or overridden before the first verifier is
+// built.
+func (a *Application) MessageVerifiers() *verifiers.Verifiers {
+	return a.messageVerifiers
+}
+
+// MessageVerifier returns Rails.application.message_verifier(name).
+func (a *Application) MessageVerifier(name string) *verifier.Verifier {
+	return a.messageVerifiers.Get(name)
+}
+
+// MessageEncryptors returns a factory of encryptors keyed like
+// ActiveSupport::MessageEncryptor.new(key_generator.generate_key(salt, 32)).
+func (a *Application) MessageEncryptors() *encryptors.Encryptors {
+	return a.messageEncryptors
+}
+
+// MessageEncryptor returns the encryptor for salt.
+func (a *Application) MessageEncryptor(salt string) *encryptor.Encryptor {
+	return a.messageEncryptors.Get(salt)
+}
+
+// SignedIDVerifier returns ActiveRecord::Base.signed_id_verifier, keyed by
+// the default signed_id_verifier_secret.
+func (a *Application) SignedIDVerifier() *signedid.Verifier {
+	return signedid.New(a.keyGenerator.GenerateKey([]byte(signedid.Salt), 64))
+}
+
+// GeneratedTokenVerifier returns ActiveRecord::Base.generated_token_verifier,
+// which signs the tokens of generates_token_for.
+func (a *Application) GeneratedTokenVerifier() *verifier.Verifier {
+	return a.MessageVerifier(tokenfor.VerifierName)
+}
+
+// SignedGlobalIDSigner signs GlobalIDs with SignedGlobalID.verifier, the
+// GlobalID::Verifier keyed by the signed_global_ids salt.
+func (a *Application) SignedGlobalIDSigner() *globalid.Signer {
+	return globalid.NewSigner(globalid.NewVerifier(a.keyGenerator.GenerateKey([]byte(globalid.Salt), 64)))
+}
+
+// ActiveStorageVerifier returns ActiveStorage.verifier, which signs blob ids
+// and the keys of Disk service URLs.
+func (a *Application) ActiveStorageVerifier() *activestorage.Verifier {
+	return activestorage.New(a.MessageVerifier(activestorage.VerifierName))
+}
+
+// TurboSignedStreamVerifier returns Turbo.signed_stream_verifier, keyed by
+// the default signed_stream_verifier_key.
+func (a *Application) TurboSignedStreamVerifier() *turbo.Verifier {
+	return turbo.New(a.keyGenerator.GenerateKey([]byte(turbo.Salt), 64))
+}
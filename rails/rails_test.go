@@ -0,0 +1,172 @@
+package rails
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/atitan/activesupport-go/actiondispatch/cookies"
+	"github.com/atitan/activesupport-go/activerecord/signedid"
+	"github.com/atitan/activesupport-go/activerecord/tokenfor"
+	"github.com/atitan/activesupport-go/activestorage"
+	"github.com/atitan/activesupport-go/globalid"
+	"github.com/atitan/activesupport-go/keygenerator"
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/encryptor"
+	"github.com/atitan/activesupport-go/message/verifier"
+	"github.com/atitan/activesupport-go/turbo"
+)
+
+var secretKeyBase = []byte("b3c631c314c0bbca50c1b2843150fe33")
+
+func TestMessageVerifier(t *testing.T) {
+	app := New(secretKeyBase, sha256.New)
+
+	serializer, err := codec.SerializerWithFallback("json_allow_marshal")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	secret := keygenerator.New(secretKeyBase, 1000, sha256.New).GenerateKey([]byte("remember_me"), 64)
+	v := verifier.New(codec.New(false, false).WithSerializer(serializer), sha1.New, secret)
+
+	want, err := v.Generate("signed message", codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	got, err := app.MessageVerifier("remember_me").Generate("signed message", codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(got) != string(want) {
+		t.Errorf("output mismatch: want %q; got %q", want, got)
+	}
+}
+
+func TestMessageEncryptor(t *testing.T) {
+	app := New(secretKeyBase, sha1.New)
+
+	secret := keygenerator.New(secretKeyBase, 1000, sha1.New).GenerateKey([]byte("credit card"), 32)
+	e := encryptor.New(codec.New(false, false), true, secret, nil, nil)
+
+	encrypted, err := e.Encrypt("encrypted message", codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var data string
+	if err := app.MessageEncryptor("credit card").Decrypt(encrypted, &data, codec.MetadataOption{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "encrypted message" {
+		t.Errorf("data mismatch: %q", data)
+	}
+}
+
+func TestCookiesConfig(t *testing.T) {
+	app := New(secretKeyBase, sha256.New)
+
+	cookie := &http.Cookie{Name: "user_id"}
+	if err := cookies.New(app.CookiesConfig()).WriteEncrypted(cookie, "45"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var data string
+	if err := cookies.New(cookies.DefaultConfig(secretKeyBase)).ReadEncrypted(cookie, &data); err != nil {
+		t.Error(err)
+		return
+	}
+	if data != "45" {
+		t.Errorf("data mismatch: %q", data)
+	}
+}
+
+func TestSignedIDVerifier(t *testing.T) {
+	app := New(secretKeyBase, sha256.New)
+
+	signed, err := app.SignedIDVerifier().Generate("User", 42, codec.MetadataOption{Purpose: "invitation"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	secret := keygenerator.New(secretKeyBase, 1000, sha256.New).GenerateKey([]byte(signedid.Salt), 64)
+
+	var id int
+	if err := signedid.New(secret).Verify(signed, "User", &id, codec.MetadataOption{Purpose: "invitation"}); err != nil || id != 42 {
+		t.Errorf("unexpected result: %d, %v", id, err)
+	}
+}
+
+func TestGeneratedTokenVerifier(t *testing.T) {
+	app := New(secretKeyBase, sha256.New)
+
+	token, err := tokenfor.New(app.GeneratedTokenVerifier(), "User", "password_reset", 15*time.Minute).Generate(42, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var data []any
+	opt := codec.MetadataOption{Purpose: "User\npassword_reset\n900"}
+	if err := app.MessageVerifier("active_record/token_for").Verify([]byte(token), &data, opt); err != nil || len(data) != 1 {
+		t.Errorf("unexpected result: %v, %v", data, err)
+	}
+}
+
+func TestSignedGlobalIDSigner(t *testing.T) {
+	app := New(secretKeyBase, sha256.New)
+	g, _ := globalid.Parse("gid://bcx/Person/5")
+
+	sgid, err := app.SignedGlobalIDSigner().Sign(g, globalid.SignOptions{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	secret := keygenerator.New(secretKeyBase, 1000, sha256.New).GenerateKey([]byte(globalid.Salt), 64)
+
+	var data string
+	opt := codec.MetadataOption{Purpose: globalid.DefaultPurpose}
+	if err := globalid.NewVerifier(secret).Verify([]byte(sgid), &data, opt); err != nil || data != g.String() {
+		t.Errorf("unexpected result: %q, %v", data, err)
+	}
+}
+
+func TestActiveStorageVerifier(t *testing.T) {
+	app := New(secretKeyBase, sha256.New)
+
+	signed, err := app.ActiveStorageVerifier().GenerateSignedBlobID(42, codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var id int
+	opt := codec.MetadataOption{Purpose: activestorage.BlobIDPurpose}
+	if err := app.MessageVerifier("ActiveStorage").Verify([]byte(signed), &id, opt); err != nil || id != 42 {
+		t.Errorf("unexpected result: %d, %v", id, err)
+	}
+}
+
+func TestTurboSignedStreamVerifier(t *testing.T) {
+	app := New(secretKeyBase, sha256.New)
+
+	signed, err := app.TurboSignedStreamVerifier().SignedStreamName("messages")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	secret := keygenerator.New(secretKeyBase, 1000, sha256.New).GenerateKey([]byte(turbo.Salt), 64)
+	if name, err := turbo.New(secret).VerifiedStreamName(signed); err != nil || name != "messages" {
+		t.Errorf("unexpected result: %q, %v", name, err)
+	}
+}
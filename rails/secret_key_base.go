@@ -0,0 +1,85 @@
+package rails
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	MissingSecretKeyBaseError = errors.New("rails: missing secret_key_base")
+	InvalidSecretKeyBaseError = errors.New("rails: secret_key_base must be a string")
+)
+
+// LocalSecretPath is where development and test environments keep a
+// generated secret_key_base, relative to the root of the app.
+const LocalSecretPath = "tmp/local_secret.txt"
+
+// SecretKeyBase resolves the secret_key_base of the app in root for env like
+// Rails.application.secret_key_base. Development and test, or any env with
+// SECRET_KEY_BASE_DUMMY set, read the local secret file, generating it when
+// missing. Other envs read SECRET_KEY_BASE, then the credentials of the env.
+func SecretKeyBase(root, env string) ([]byte, error) {
+	if _, dummy := os.LookupEnv("SECRET_KEY_BASE_DUMMY"); dummy || env == "development" || env == "test" {
+		return localSecret(root)
+	}
+
+	if secretKeyBase, ok := os.LookupEnv("SECRET_KEY_BASE"); ok {
+		if secretKeyBase == "" {
+			return nil, fmt.Errorf("%w for '%s' environment", MissingSecretKeyBaseError, env)
+		}
+		return []byte(secretKeyBase), nil
+	}
+
+	value, err := Credentials(root, env, false).Dig("secret_key_base")
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("%w for '%s' environment", MissingSecretKeyBaseError, env)
+	case string:
+		if value == "" {
+			return nil, fmt.Errorf("%w for '%s' environment", MissingSecretKeyBaseError, env)
+		}
+		return []byte(value), nil
+	default:
+		return nil, fmt.Errorf("%w for '%s' environment", InvalidSecretKeyBaseError, env)
+	}
+}
+
+func localSecret(root string) ([]byte, error) {
+	path := filepath.Join(root, LocalSecretPath)
+
+	secret, err := os.ReadFile(path)
+	if err == nil {
+		return secret, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	secret = []byte(GenerateSecretKeyBase())
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, secret, 0600); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// GenerateSecretKeyBase returns a new secret_key_base, like bin/rails secret.
+func GenerateSecretKeyBase() string {
+	b := make([]byte, 64)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,88 @@
+package rails
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecretKeyBaseLocal(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("SECRET_KEY_BASE", "ignored")
+
+	secretKeyBase, err := SecretKeyBase(root, "development")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(secretKeyBase) != 128 {
+		t.Errorf("unexpected secret_key_base: %q", secretKeyBase)
+	}
+
+	stored, err := os.ReadFile(filepath.Join(root, LocalSecretPath))
+	if err != nil || string(stored) != string(secretKeyBase) {
+		t.Errorf("local secret mismatch: %q, %v", stored, err)
+	}
+
+	again, err := SecretKeyBase(root, "test")
+	if err != nil || string(again) != string(secretKeyBase) {
+		t.Errorf("local secret not reused: %q, %v", again, err)
+	}
+}
+
+func TestSecretKeyBaseDummy(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("SECRET_KEY_BASE_DUMMY", "1")
+
+	if _, err := SecretKeyBase(root, "production"); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, LocalSecretPath)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSecretKeyBaseEnv(t *testing.T) {
+	t.Setenv("SECRET_KEY_BASE", "from env")
+
+	secretKeyBase, err := SecretKeyBase(t.TempDir(), "production")
+	if err != nil || string(secretKeyBase) != "from env" {
+		t.Errorf("unexpected secret_key_base: %q, %v", secretKeyBase, err)
+	}
+
+	t.Setenv("SECRET_KEY_BASE", "")
+	if _, err := SecretKeyBase(t.TempDir(), "production"); !errors.Is(err, MissingSecretKeyBaseError) {
+		t.Errorf("expected MissingSecretKeyBaseError, got: %v", err)
+	}
+}
+
+func TestSecretKeyBaseCredentials(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("SECRET_KEY_BASE", "")
+	os.Unsetenv("SECRET_KEY_BASE")
+	t.Setenv(MasterKeyEnv, "4aa19bef10a27fd29e09058b10e8c279")
+
+	if _, err := SecretKeyBase(root, "production"); !errors.Is(err, MissingSecretKeyBaseError) {
+		t.Errorf("expected MissingSecretKeyBaseError, got: %v", err)
+	}
+
+	credentials := Credentials(root, "production", false)
+	if err := credentials.Write([]byte("secret_key_base: from credentials\n")); err != nil {
+		t.Error(err)
+		return
+	}
+
+	secretKeyBase, err := SecretKeyBase(root, "production")
+	if err != nil || string(secretKeyBase) != "from credentials" {
+		t.Errorf("unexpected secret_key_base: %q, %v", secretKeyBase, err)
+	}
+
+	if err := credentials.Write([]byte("secret_key_base: 123\n")); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := SecretKeyBase(root, "production"); !errors.Is(err, InvalidSecretKeyBaseError) {
+		t.Errorf("expected InvalidSecretKeyBaseError, got: %v", err)
+	}
+}
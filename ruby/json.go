@@ -0,0 +1,123 @@
+package ruby
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var NotJSONObjectError = errors.New("ruby: not a JSON object")
+
+// MarshalJSON writes the pairs in order, with keys converted like to_s.
+func (h Hash) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+
+	for i, pair := range h.Pairs {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		var key string
+		switch k := pair.Key.(type) {
+		case string:
+			key = k
+		case Symbol:
+			key = string(k)
+		default:
+			key = fmt.Sprint(k)
+		}
+
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(encodedKey)
+		b.WriteByte(':')
+
+		value, err := json.Marshal(pair.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+
+	b.WriteByte('}')
+
+	return b.Bytes(), nil
+}
+
+// UnmarshalJSON reads a JSON object keeping the order of its keys. Nested
+// objects become *Hash, arrays []any, and integers int64.
+func (h *Hash) UnmarshalJSON(data []byte) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	v, err := decodeJSON(d)
+	if err != nil {
+		return err
+	}
+
+	decoded, ok := v.(*Hash)
+	if !ok {
+		return NotJSONObjectError
+	}
+
+	*h = *decoded
+
+	return nil
+}
+
+func decodeJSON(d *json.Decoder) (any, error) {
+	token, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		switch token {
+		case '{':
+			h := NewHash()
+			for d.More() {
+				key, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				value, err := decodeJSON(d)
+				if err != nil {
+					return nil, err
+				}
+
+				h.Set(key.(string), value)
+			}
+
+			_, err := d.Token()
+			return h, err
+		case '[':
+			a := []any{}
+			for d.More() {
+				value, err := decodeJSON(d)
+				if err != nil {
+					return nil, err
+				}
+
+				a = append(a, value)
+			}
+
+			_, err := d.Token()
+			return a, err
+		}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(token), 10, 64); err == nil {
+			return i, nil
+		}
+
+		return token.Float64()
+	}
+
+	return token, nil
+}
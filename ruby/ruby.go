@@ -0,0 +1,73 @@
+// Package ruby holds the Go representation of Ruby core values shared by the
+// Marshal and MessagePack serializers.
+package ruby
+
+import "reflect"
+
+type Symbol string
+
+// ASCIIString is a String in the US-ASCII encoding, like the ones returned by
+// Time#iso8601. Serializers other than Marshal treat it as a plain string.
+type ASCIIString string
+
+// Hash is an insertion-ordered Ruby Hash. Default is the value set through
+// Hash.new(default), if any.
+type Hash struct {
+	Pairs   []Pair
+	Default any
+}
+
+type Pair struct {
+	Key   any
+	Value any
+}
+
+func NewHash(pairs ...Pair) *Hash {
+	return &Hash{Pairs: pairs}
+}
+
+func (h *Hash) Set(key, value any) {
+	for i := range h.Pairs {
+		if sameKey(h.Pairs[i].Key, key) {
+			h.Pairs[i].Value = value
+			return
+		}
+	}
+
+	h.Pairs = append(h.Pairs, Pair{Key: key, Value: value})
+}
+
+// Get looks up a key, treating String and Symbol keys alike the way
+// ActiveSupport::HashWithIndifferentAccess does.
+func (h *Hash) Get(key string) (any, bool) {
+	for _, pair := range h.Pairs {
+		switch k := pair.Key.(type) {
+		case string:
+			if k == key {
+				return pair.Value, true
+			}
+		case Symbol:
+			if string(k) == key {
+				return pair.Value, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func (h *Hash) Len() int {
+	return len(h.Pairs)
+}
+
+func sameKey(a, b any) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	if !reflect.TypeOf(a).Comparable() || !reflect.TypeOf(b).Comparable() {
+		return false
+	}
+
+	return a == b
+}
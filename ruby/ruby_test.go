@@ -0,0 +1,70 @@
+package ruby
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestHashSetGet(t *testing.T) {
+	h := NewHash()
+	h.Set("a", 1)
+	h.Set(Symbol("b"), 2)
+	h.Set("a", 3)
+	h.Set([]any{1}, 4)
+
+	if h.Len() != 3 {
+		t.Errorf("unexpected length: %d", h.Len())
+	}
+
+	if v, ok := h.Get("a"); !ok || v != 3 {
+		t.Errorf("unexpected value for a: %v", v)
+	}
+
+	if v, ok := h.Get("b"); !ok || v != 2 {
+		t.Errorf("unexpected value for b: %v", v)
+	}
+
+	if _, ok := h.Get("c"); ok {
+		t.Errorf("unexpected value for c")
+	}
+}
+
+func TestHashJSON(t *testing.T) {
+	h := NewHash(
+		Pair{Key: Symbol("resize_to_limit"), Value: []any{100, 100}},
+		Pair{Key: "format", Value: Symbol("png")},
+		Pair{Key: Symbol("saver"), Value: NewHash(Pair{Key: Symbol("quality"), Value: 80})},
+	)
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	want := `{"resize_to_limit":[100,100],"format":"png","saver":{"quality":80}}`
+	if string(data) != want {
+		t.Errorf("output mismatch: want %s; got %s", want, data)
+	}
+
+	var decoded Hash
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Error(err)
+		return
+	}
+
+	roundTripped, err := json.Marshal(decoded)
+	if err != nil || string(roundTripped) != want {
+		t.Errorf("output mismatch: want %s; got %s, %v", want, roundTripped, err)
+	}
+
+	if v, _ := decoded.Get("resize_to_limit"); !reflect.DeepEqual(v, []any{int64(100), int64(100)}) {
+		t.Errorf("unexpected value: %#v", v)
+	}
+
+	if err := json.Unmarshal([]byte(`[1]`), &decoded); !errors.Is(err, NotJSONObjectError) {
+		t.Errorf("expected NotJSONObjectError, got: %v", err)
+	}
+}
@@ -1,11 +1,25 @@
 require 'active_support'
+require 'active_support/message_pack'
+require 'active_support/testing/time_helpers'
+require 'active_support/core_ext/hash/keys'
+require 'active_record'
+require 'globalid'
 require 'json'
+require 'openssl'
 require 'fileutils'
 
+include ActiveSupport::Testing::TimeHelpers
+
 FileUtils.rm_rf('message/encryptor/testdata')
 FileUtils.mkdir_p('message/encryptor/testdata')
 FileUtils.rm_rf('message/verifier/testdata')
 FileUtils.mkdir_p('message/verifier/testdata')
+FileUtils.rm_rf('globalid/testdata')
+FileUtils.mkdir_p('globalid/testdata')
+FileUtils.rm_rf('activestorage/testdata')
+FileUtils.mkdir_p('activestorage/testdata')
+FileUtils.rm_rf('activerecord/encryption/testdata')
+FileUtils.mkdir_p('activerecord/encryption/testdata')
 
 matrix = {
   TestVerifyModernSimpleString: {
@@ -38,18 +52,53 @@ matrix = {
   TestVerifyExpired: {
     url_safe: false, legacy: false, data: 'signed message', opt: { expires_at: Time.new(2007, 1, 1, 0, 0, 0) },
   },
+  TestVerifyMarshalSimpleEnvelope: {
+    url_safe: false, legacy: false, data: 'signed message', opt: { purpose: 'pizza' }, serializer: Marshal,
+  },
+  TestVerifyMarshalComplexEnvelope: {
+    url_safe: false, legacy: false, data: { 'ab' => 123, 'cd' => 'yellow', 'ef' => true, 'gh' => nil }, opt: { purpose: 'pizza' }, serializer: Marshal,
+  },
+  TestVerifyMarshalLegacyComplexEnvelope: {
+    url_safe: false, legacy: true, data: { 'ab' => 123, 'cd' => 'yellow', 'ef' => true, 'gh' => nil }, opt: { purpose: 'pizza' }, serializer: Marshal,
+  },
+  TestVerifyMessagePackSimpleEnvelope: {
+    url_safe: false, legacy: false, data: 'signed message', opt: { purpose: 'pizza' }, serializer: ActiveSupport::MessagePack,
+  },
+  TestVerifyMessagePackComplexEnvelope: {
+    url_safe: false, legacy: false, data: { 'ab' => 123, 'cd' => 'yellow', 'ef' => true, 'gh' => nil }, opt: { purpose: 'pizza' }, serializer: ActiveSupport::MessagePack,
+  },
+  TestVerifyMessagePackLegacyComplexEnvelope: {
+    url_safe: false, legacy: true, data: { 'ab' => 123, 'cd' => 'yellow', 'ef' => true, 'gh' => nil }, opt: { purpose: 'pizza' }, serializer: ActiveSupport::MessagePack,
+  },
+  TestVerifyModernFrozenExpiresIn: {
+    url_safe: false, legacy: false, data: 'signed message', opt: { expires_in: 3600, purpose: 'pizza' }, travel_to: Time.utc(2007, 1, 1),
+  },
+  TestVerifyLegacyFrozenExpiresIn: {
+    url_safe: false, legacy: true, data: 'signed message', opt: { expires_in: 3600, purpose: 'pizza' }, travel_to: Time.utc(2007, 1, 1),
+  },
+  TestVerifyMarshalFrozenExpiresIn: {
+    url_safe: false, legacy: false, data: 'signed message', opt: { expires_in: 3600, purpose: 'pizza' }, travel_to: Time.utc(2007, 1, 1), serializer: Marshal,
+  },
+  TestVerifyMessagePackFrozenExpiresIn: {
+    url_safe: false, legacy: false, data: 'signed message', opt: { expires_in: 3600, purpose: 'pizza' }, travel_to: Time.utc(2007, 1, 1), serializer: ActiveSupport::MessagePack,
+  },
 }
 
 matrix.each do |name, setup|
   v = ActiveSupport::MessageVerifier.new(
     '12345678',
     digest: 'SHA256',
-    serializer: JSON,
+    serializer: setup.fetch(:serializer, JSON),
     url_safe: setup[:url_safe],
     force_legacy_metadata_serializer: setup[:legacy],
   )
 
-  out = v.generate(setup[:data], **setup[:opt])
+  out =
+    if setup[:travel_to]
+      travel_to(setup[:travel_to]) { v.generate(setup[:data], **setup[:opt]) }
+    else
+      v.generate(setup[:data], **setup[:opt])
+    end
 
   File.write("message/verifier/testdata/#{name}.txt", out)
 end
@@ -76,6 +125,12 @@ matrix = {
   TestDecryptGCM256: {
     cipher: 'aes-256-gcm', key: '12345678901234567890123456789012',
   },
+  TestDecryptGCM256Marshal: {
+    cipher: 'aes-256-gcm', key: '12345678901234567890123456789012', serializer: Marshal,
+  },
+  TestDecryptGCM256MessagePack: {
+    cipher: 'aes-256-gcm', key: '12345678901234567890123456789012', serializer: ActiveSupport::MessagePack,
+  },
 }
 
 matrix.each do |name, setup|
@@ -84,7 +139,7 @@ matrix.each do |name, setup|
     setup[:hmac_key],
     cipher: setup[:cipher],
     digest: setup[:digest],
-    serializer: JSON,
+    serializer: setup.fetch(:serializer, JSON),
     url_safe: false,
     force_legacy_metadata_serializer: false,
   )
@@ -93,3 +148,51 @@ matrix.each do |name, setup|
 
   File.write("message/encryptor/testdata/#{name}.txt", out)
 end
+
+# Purpose a~~~ makes the padded URL-safe payload contain "-"
+sgid_verifier = GlobalID::Verifier.new('12345678', serializer: JSON, force_legacy_metadata_serializer: false)
+sgid = SignedGlobalID.new('gid://bcx/Person/5', verifier: sgid_verifier, for: 'a~~~', expires_at: Time.utc(2100, 1, 1))
+
+File.write('globalid/testdata/TestSignedRails.txt', sgid.to_s)
+
+# ActiveStorage::Variation#digest of a variation decoded from a JSON key
+transformations = JSON.parse('{"resize_to_limit":[100,100],"saver":{"quality":80}}').deep_symbolize_keys
+
+File.write('activestorage/testdata/TestVariationDigestRails.txt', OpenSSL::Digest::SHA1.base64digest(Marshal.dump(transformations)))
+
+# ActiveRecord::Encryption messages, with the keys of the Go tests
+ActiveRecord::Encryption.configure(
+  primary_key: 'EGY8WhulUOXixybod7ZWwMIL68R9o5kC',
+  deterministic_key: '0yxGVmmJYHdGKpUuPpkt0k1iDcOHaGwi',
+  key_derivation_salt: 'xEY0dt6TZcAMg52K7O84wYzkjvbA62Hz',
+  hash_digest_class: OpenSSL::Digest::SHA256,
+)
+
+ar_encryptor = ActiveRecord::Encryption::Encryptor.new
+ar_matrix = {
+  TestDecryptRails: ['john@example.com', {}],
+  TestDecryptRailsCompressed: ['long text ' * 20, {}],
+  TestDecryptRailsBinary: ["\xff\xfe".b, {}],
+  TestDeterministicRails: [
+    'john@example.com',
+    {
+      key_provider: ActiveRecord::Encryption::DeterministicKeyProvider.new(ActiveRecord::Encryption.config.deterministic_key),
+      cipher_options: { deterministic: true },
+    },
+  ],
+}
+
+ar_matrix.each do |name, (clear_text, options)|
+  File.write("activerecord/encryption/testdata/#{name}.txt", ar_encryptor.encrypt(clear_text, **options))
+end
+
+ActiveRecord::Encryption.config.store_key_references = true
+
+ar_matrix = {
+  TestDecryptRailsKeyReference: ActiveRecord::Encryption::DerivedSecretKeyProvider.new(ActiveRecord::Encryption.config.primary_key),
+  TestDecryptRailsEnvelope: ActiveRecord::Encryption::EnvelopeEncryptionKeyProvider.new,
+}
+
+ar_matrix.each do |name, key_provider|
+  File.write("activerecord/encryption/testdata/#{name}.txt", ar_encryptor.encrypt('john@example.com', key_provider:))
+end
@@ -1,4 +1,5 @@
 require 'active_support'
+require 'active_support/message_pack'
 require 'json'
 
 matrix = {
@@ -32,6 +33,24 @@ matrix = {
   TestGenerateExpired: {
     url_safe: false, legacy: false, data: 'signed message', opt: {}, expect_invalid_sig: true,
   },
+  TestGenerateMarshalSimpleEnvelope: {
+    url_safe: false, legacy: false, data: 'signed message', opt: { purpose: 'pizza' }, serializer: Marshal,
+  },
+  TestGenerateMarshalComplexEnvelope: {
+    url_safe: false, legacy: false, data: { 'ab' => 123, 'cd' => 'yellow', 'ef' => true, 'gh' => nil }, opt: { purpose: 'pizza' }, serializer: Marshal,
+  },
+  TestGenerateMarshalLegacyComplexEnvelope: {
+    url_safe: false, legacy: true, data: { 'ab' => 123, 'cd' => 'yellow', 'ef' => true, 'gh' => nil }, opt: { purpose: 'pizza' }, serializer: Marshal,
+  },
+  TestGenerateMessagePackSimpleEnvelope: {
+    url_safe: false, legacy: false, data: 'signed message', opt: { purpose: 'pizza' }, serializer: ActiveSupport::MessagePack,
+  },
+  TestGenerateMessagePackComplexEnvelope: {
+    url_safe: false, legacy: false, data: { 'ab' => 123, 'cd' => 'yellow', 'ef' => true, 'gh' => nil }, opt: { purpose: 'pizza' }, serializer: ActiveSupport::MessagePack,
+  },
+  TestGenerateMessagePackLegacyComplexEnvelope: {
+    url_safe: false, legacy: true, data: { 'ab' => 123, 'cd' => 'yellow', 'ef' => true, 'gh' => nil }, opt: { purpose: 'pizza' }, serializer: ActiveSupport::MessagePack,
+  },
 }
 
 matrix.each do |name, setup|
@@ -40,7 +59,7 @@ matrix.each do |name, setup|
   v = ActiveSupport::MessageVerifier.new(
     '12345678',
     digest: 'SHA256',
-    serializer: JSON,
+    serializer: setup.fetch(:serializer, JSON),
     url_safe: setup[:url_safe],
     force_legacy_metadata_serializer: setup[:legacy],
   )
@@ -78,6 +97,12 @@ matrix = {
   TestEncryptGCM256: {
     cipher: 'aes-256-gcm', key: '12345678901234567890123456789012',
   },
+  TestEncryptGCM256Marshal: {
+    cipher: 'aes-256-gcm', key: '12345678901234567890123456789012', serializer: Marshal,
+  },
+  TestEncryptGCM256MessagePack: {
+    cipher: 'aes-256-gcm', key: '12345678901234567890123456789012', serializer: ActiveSupport::MessagePack,
+  },
 }
 
 matrix.each do |name, setup|
@@ -88,7 +113,7 @@ matrix.each do |name, setup|
     setup[:hmac_key],
     cipher: setup[:cipher],
     digest: setup[:digest],
-    serializer: JSON,
+    serializer: setup.fetch(:serializer, JSON),
     url_safe: false,
     force_legacy_metadata_serializer: false,
   )
@@ -0,0 +1,65 @@
+// Package timehelpers mirrors ActiveSupport::Testing::TimeHelpers with a
+// Clock which can be handed to codec.Codec.WithClock and moved around in tests.
+package timehelpers
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock follows the real time until it is frozen or travelled.
+type Clock struct {
+	mu     sync.Mutex
+	frozen *time.Time
+}
+
+func New() *Clock {
+	return &Clock{}
+}
+
+// Now returns the time of the clock. It has the signature of time.Now.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen != nil {
+		return *c.frozen
+	}
+
+	return time.Now()
+}
+
+// TravelTo freezes the clock at t, like travel_to.
+func (c *Clock) TravelTo(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.frozen = &t
+}
+
+// Travel freezes the clock at d from its current time, like travel.
+func (c *Clock) Travel(d time.Duration) {
+	c.TravelTo(c.Now().Add(d))
+}
+
+// FreezeTime freezes the clock at its current time, like freeze_time.
+func (c *Clock) FreezeTime() {
+	c.TravelTo(c.Now())
+}
+
+// TravelBack returns the clock to the real time, like travel_back.
+func (c *Clock) TravelBack() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.frozen = nil
+}
+
+// TravelToFunc freezes the clock at t while fn runs, then travels back, like
+// travel_to with a block.
+func (c *Clock) TravelToFunc(t time.Time, fn func()) {
+	c.TravelTo(t)
+	defer c.TravelBack()
+
+	fn()
+}
@@ -0,0 +1,52 @@
+package timehelpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTravel(t *testing.T) {
+	c := New()
+	moment := time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c.TravelTo(moment)
+	if now := c.Now(); !now.Equal(moment) {
+		t.Errorf("time mismatch: %v", now)
+	}
+
+	c.Travel(time.Hour)
+	if now := c.Now(); !now.Equal(moment.Add(time.Hour)) {
+		t.Errorf("time mismatch: %v", now)
+	}
+
+	c.TravelBack()
+	if now := c.Now(); now.Year() == 2007 {
+		t.Errorf("time mismatch: %v", now)
+	}
+}
+
+func TestFreezeTime(t *testing.T) {
+	c := New()
+	c.FreezeTime()
+
+	frozen := c.Now()
+	time.Sleep(time.Millisecond)
+	if now := c.Now(); !now.Equal(frozen) {
+		t.Errorf("time mismatch: %v, %v", now, frozen)
+	}
+}
+
+func TestTravelToFunc(t *testing.T) {
+	c := New()
+	moment := time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c.TravelToFunc(moment, func() {
+		if now := c.Now(); !now.Equal(moment) {
+			t.Errorf("time mismatch: %v", now)
+		}
+	})
+
+	if now := c.Now(); now.Equal(moment) {
+		t.Errorf("clock did not travel back: %v", now)
+	}
+}
@@ -0,0 +1,79 @@
+// Package turbo mirrors the signed stream names of turbo-rails, which
+// turbo_stream_from hands to the browser to subscribe to Turbo::StreamsChannel.
+package turbo
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/atitan/activesupport-go/globalid"
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+// Salt derives Turbo.signed_stream_verifier_key from the key generator of a
+// Rails application.
+const Salt = "turbo/signed_stream_verifier_key"
+
+type Verifier struct {
+	verifier *verifier.Verifier
+}
+
+// New returns Turbo.signed_stream_verifier, a JSON MessageVerifier signing
+// with SHA256.
+func New(secret []byte) *Verifier {
+	return &Verifier{verifier: verifier.New(codec.New(false, false).WithSerializer(codec.JSON), sha256.New, secret)}
+}
+
+// SignedStreamName signs the stream name of streamables, like
+// signed_stream_name.
+func (v *Verifier) SignedStreamName(streamables ...any) (string, error) {
+	signed, err := v.verifier.Generate(StreamName(streamables...), codec.MetadataOption{})
+	if err != nil {
+		return "", err
+	}
+
+	return string(signed), nil
+}
+
+// VerifiedStreamName returns the stream name of signedStreamName, like
+// verified_stream_name.
+func (v *Verifier) VerifiedStreamName(signedStreamName string) (string, error) {
+	var name string
+	if err := v.verifier.Verify([]byte(signedStreamName), &name, codec.MetadataOption{}); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// StreamName joins streamables with ":", like stream_name_from. A
+// *globalid.GlobalID stands for a record and gives its to_gid_param, a slice
+// is joined recursively, nil is empty, and anything else is formatted like
+// to_param.
+func StreamName(streamables ...any) string {
+	names := make([]string, len(streamables))
+	for i, streamable := range streamables {
+		names[i] = streamName(streamable)
+	}
+
+	return strings.Join(names, ":")
+}
+
+func streamName(streamable any) string {
+	switch s := streamable.(type) {
+	case nil:
+		return ""
+	case *globalid.GlobalID:
+		return s.Param()
+	case []any:
+		return StreamName(s...)
+	case []string:
+		return strings.Join(s, ":")
+	case string:
+		return s
+	default:
+		return fmt.Sprint(s)
+	}
+}
@@ -0,0 +1,57 @@
+package turbo
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/atitan/activesupport-go/globalid"
+	"github.com/atitan/activesupport-go/message/codec"
+	"github.com/atitan/activesupport-go/message/verifier"
+)
+
+func TestStreamName(t *testing.T) {
+	board, _ := globalid.Parse("gid://bcx/Board/5")
+
+	for want, streamables := range map[string][]any{
+		"messages":                         {"messages"},
+		"Z2lkOi8vYmN4L0JvYXJkLzU:messages": {board, "messages"},
+		"Z2lkOi8vYmN4L0JvYXJkLzU:cards:42": {[]any{board, []string{"cards"}}, 42},
+		":messages":                        {nil, "messages"},
+	} {
+		if got := StreamName(streamables...); got != want {
+			t.Errorf("want %q; got %q", want, got)
+		}
+	}
+}
+
+func TestSignedStreamName(t *testing.T) {
+	secret := []byte("turbo secret")
+	v := New(secret)
+	board, _ := globalid.Parse("gid://bcx/Board/5")
+
+	signed, err := v.SignedStreamName(board, "messages")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Turbo.signed_stream_verifier.generate("Z2lkOi8vYmN4L0JvYXJkLzU:messages")
+	want, err := verifier.New(codec.New(false, false).WithSerializer(codec.JSON), sha256.New, secret).
+		Generate("Z2lkOi8vYmN4L0JvYXJkLzU:messages", codec.MetadataOption{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if signed != string(want) {
+		t.Errorf("output mismatch: want %q; got %q", want, signed)
+	}
+
+	if name, err := v.VerifiedStreamName(signed); err != nil || name != "Z2lkOi8vYmN4L0JvYXJkLzU:messages" {
+		t.Errorf("unexpected result: %q, %v", name, err)
+	}
+
+	if _, err := New([]byte("other secret")).VerifiedStreamName(signed); !errors.Is(err, verifier.InvalidSignatureError) {
+		t.Errorf("expected InvalidSignatureError, got: %v", err)
+	}
+}